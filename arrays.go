@@ -0,0 +1,209 @@
+package gdbasic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultArrayBound is the upper bound of each dimension of an array that is
+// used without being dimensioned by DIM.
+const defaultArrayBound = 10
+
+// Array is a variable holding a value for each combination of subscripts
+// from Base to the upper bound of each dimension.
+type Array struct {
+	// Base is the lower bound of every dimension, 0 or 1, as set by OPTION
+	// BASE when the array was made.
+	Base int
+	// Bounds are the upper bounds of each dimension.
+	Bounds []int
+	// Values are the elements, with the last subscript varying fastest.
+	Values []Value
+}
+
+func newArray(name string, base int, bounds []int) *Array {
+	size := 1
+	for _, b := range bounds {
+		size *= b - base + 1
+	}
+	arr := &Array{Base: base, Bounds: bounds, Values: make([]Value, size)}
+	for i := range arr.Values {
+		arr.Values[i] = zeroValue(name)
+	}
+	return arr
+}
+
+// offset returns the position in Values of the element at the subscripts.
+func (arr *Array) offset(subscripts []int) (int, error) {
+	if len(subscripts) != len(arr.Bounds) {
+		return 0, fmt.Errorf("wrong number of subscripts, expected %d got %d", len(arr.Bounds), len(subscripts))
+	}
+	offset := 0
+	for i, sub := range subscripts {
+		if sub < arr.Base || sub > arr.Bounds[i] {
+			return 0, fmt.Errorf("subscript out of range: %d", sub)
+		}
+		offset = offset*(arr.Bounds[i]-arr.Base+1) + sub - arr.Base
+	}
+	return offset, nil
+}
+
+func (arr *Array) String() string {
+	bounds := make([]string, len(arr.Bounds))
+	for i, b := range arr.Bounds {
+		bounds[i] = fmt.Sprint(b)
+		if arr.Base != 0 {
+			bounds[i] = fmt.Sprintf("%d TO %d", arr.Base, b)
+		}
+	}
+	items := make([]string, len(arr.Values))
+	for i, val := range arr.Values {
+		items[i] = val.String()
+	}
+	return fmt.Sprintf("(%s) [%s]", strings.Join(bounds, ","), strings.Join(items, " "))
+}
+
+// array returns the named array, creating it with the default bounds if it
+// has not been dimensioned.
+func (bob *Interpreter) array(name string, dims int) *Array {
+	if arr, ok := bob.Arrays[name]; ok {
+		return arr
+	}
+	bounds := make([]int, dims)
+	for i := range bounds {
+		bounds[i] = defaultArrayBound
+	}
+	if bob.Arrays == nil {
+		bob.Arrays = map[string]*Array{}
+	}
+	bob.Arrays[name] = newArray(name, bob.base, bounds)
+	return bob.Arrays[name]
+}
+
+// subscripts converts the evaluated subscripts to integers.
+func subscripts(name string, args []Value) ([]int, error) {
+	subs := make([]int, len(args))
+	for i, arg := range args {
+		if arg.IsStr {
+			return nil, fmt.Errorf("type mismatch: subscript %d of %s", i+1, name)
+		}
+		subs[i] = arg.int()
+	}
+	return subs, nil
+}
+
+// arrayElement returns the element of the named array at the subscripts.
+func (bob *Interpreter) arrayElement(name string, args []Value) (Value, error) {
+	subs, err := subscripts(name, args)
+	if err != nil {
+		return Value{}, err
+	}
+	arr := bob.array(name, len(subs))
+	offset, err := arr.offset(subs)
+	if err != nil {
+		return Value{}, fmt.Errorf("%s: %v", name, err)
+	}
+	return arr.Values[offset], nil
+}
+
+// setArrayElement sets the element of the named array at the subscripts.
+func (bob *Interpreter) setArrayElement(name string, args []Value, val Value) error {
+	subs, err := subscripts(name, args)
+	if err != nil {
+		return err
+	}
+	arr := bob.array(name, len(subs))
+	offset, err := arr.offset(subs)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	arr.Values[offset] = val
+	return nil
+}
+
+// DimInstruction dimensions arrays, giving the upper bound of each dimension.
+type DimInstruction struct {
+	Arrays []FuncExpression
+}
+
+func (di DimInstruction) Execute(intp *Interpreter) error {
+	for _, decl := range di.Arrays {
+		if _, ok := intp.Arrays[decl.Name]; ok {
+			return fmt.Errorf("duplicate definition: %s", decl.Name)
+		}
+		bounds := make([]int, len(decl.Args))
+		for i, arg := range decl.Args {
+			b, err := evalInt(intp, arg)
+			if err != nil {
+				return err
+			}
+			if b < intp.base {
+				return fmt.Errorf("subscript out of range: %s", decl)
+			}
+			bounds[i] = b
+		}
+		if intp.Arrays == nil {
+			intp.Arrays = map[string]*Array{}
+		}
+		intp.Arrays[decl.Name] = newArray(decl.Name, intp.base, bounds)
+	}
+	return nil
+}
+
+func (di DimInstruction) String() string {
+	decls := make([]string, len(di.Arrays))
+	for i, decl := range di.Arrays {
+		decls[i] = decl.String()
+	}
+	return "DIM " + strings.Join(decls, ", ")
+}
+
+func NewDimInstruction(_ int, remainder string) (*DimInstruction, error) {
+	// DIM A(10), B$(3, 4)
+	exprs, err := ParseExpressionList(remainder)
+	if err != nil {
+		return nil, err
+	}
+	di := new(DimInstruction)
+	for _, arg := range exprs {
+		decl, ok := arg.(FuncExpression)
+		if !ok || len(decl.Args) == 0 {
+			return nil, fmt.Errorf("dim has a bad array `%s`", arg)
+		}
+		di.Arrays = append(di.Arrays, decl)
+	}
+	return di, nil
+}
+
+// OptionBaseInstruction sets the lower bound of arrays to 0 or 1. It must be
+// run before any array is dimensioned or used.
+type OptionBaseInstruction struct {
+	Base int
+}
+
+func (obi OptionBaseInstruction) Execute(intp *Interpreter) error {
+	if len(intp.Arrays) != 0 {
+		return fmt.Errorf("OPTION BASE after arrays are dimensioned")
+	}
+	intp.base = obi.Base
+	return nil
+}
+
+func (obi OptionBaseInstruction) String() string {
+	return fmt.Sprintf("OPTION BASE %d", obi.Base)
+}
+
+func NewOptionBaseInstruction(_ int, remainder string) (*OptionBaseInstruction, error) {
+	// OPTION BASE 1
+	before, arg, ok := cutKeyword(remainder, "BASE")
+	if !ok || strings.TrimSpace(before) != "" {
+		return nil, fmt.Errorf("unknown option `%s`", remainder)
+	}
+	arg = strings.TrimSpace(arg)
+	base, err := strconv.Atoi(arg)
+	if err != nil || (base != 0 && base != 1) {
+		return nil, fmt.Errorf("OPTION BASE must be 0 or 1 not `%s`", arg)
+	}
+	return &OptionBaseInstruction{Base: base}, nil
+}
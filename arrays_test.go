@@ -0,0 +1,138 @@
+package gdbasic
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPrintArrayElement(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{
+			name:    "variable index",
+			program: []string{`10 DIM A(3)`, `20 LET A(2)=42`, `30 LET I=2`, `40 PRINT A(I)`},
+			want:    "42\n",
+		},
+		{
+			name:    "expression index",
+			program: []string{`10 DIM A(3)`, `20 LET A(2)=42`, `30 LET I=1`, `40 PRINT A(I+1); A(I)`},
+			want:    "420\n",
+		},
+		{
+			name: "index changing in a loop",
+			program: []string{
+				`10 DIM A$(2)`,
+				`20 LET A$(0)="X"`,
+				`30 LET A$(1)="Y"`,
+				`40 LET A$(2)="Z"`,
+				`50 FOR I=0 TO 2`,
+				`60 PRINT A$(I);`,
+				`70 NEXT I`,
+				`80 PRINT`,
+			},
+			want: "XYZ\n",
+		},
+		{
+			name:    "in an expression",
+			program: []string{`10 DIM A(3)`, `20 LET A(2)=40`, `30 LET I=2`, `40 PRINT A(I)+2`},
+			want:    "42\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runProgram(t, tc.program...); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestArrayIndexErrors(t *testing.T) {
+	for _, program := range [][]string{
+		{`10 DIM A(3)`, `20 LET I=4`, `30 PRINT A(I)`},
+		{`10 DIM A(3)`, `20 PRINT A(-1)`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		if err := bob.Run(); err == nil {
+			t.Errorf("%q: expected an error", program)
+		}
+	}
+}
+
+func TestOptionBase(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		size  int
+		first int
+	}{
+		{"default", `REM`, 4, 0},
+		{"base 0", `OPTION BASE 0`, 4, 0},
+		{"base 1", `OPTION BASE 1`, 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t,
+				`10 `+tt.base,
+				`20 DIM A(3), B(2, 3)`,
+				fmt.Sprintf(`30 LET A(%d)=7`, tt.first),
+				`40 LET A(3)=9`,
+				fmt.Sprintf(`50 PRINT A(%d); A(3)`, tt.first),
+			)
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if out.String() != "79\n" {
+				t.Errorf("printed %q, want %q", out.String(), "79\n")
+			}
+			if got := len(bob.Arrays["A"].Values); got != tt.size {
+				t.Errorf("A has %d elements, want %d", got, tt.size)
+			}
+			if got, want := len(bob.Arrays["B"].Values), (3-tt.first)*(4-tt.first); got != want {
+				t.Errorf("B has %d elements, want %d", got, want)
+			}
+			// an undimensioned array gets the base too
+			if err := bob.Execute(`PRINT C(10)`); err != nil {
+				t.Fatalf("PRINT C(10): %v", err)
+			}
+			if got := len(bob.Arrays["C"].Values); got != 11-tt.first {
+				t.Errorf("C has %d elements, want %d", got, 11-tt.first)
+			}
+		})
+	}
+}
+
+func TestOptionBaseErrors(t *testing.T) {
+	for _, program := range [][]string{
+		// below the base
+		{`10 OPTION BASE 1`, `20 DIM A(3)`, `30 PRINT A(0)`},
+		{`10 OPTION BASE 1`, `20 DIM A(0)`},
+		{`10 OPTION BASE 1`, `20 LET A(0)=1`},
+		// after arrays are dimensioned or used
+		{`10 DIM A(3)`, `20 OPTION BASE 1`},
+		{`10 LET A(1)=1`, `20 OPTION BASE 1`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		if err := bob.Run(); err == nil {
+			t.Errorf("%q: expected an error", program)
+		}
+	}
+	for _, line := range []string{
+		`10 OPTION BASE 2`,
+		`10 OPTION BASE -1`,
+		`10 OPTION BASE`,
+		`10 OPTION BASE X`,
+		`10 OPTION EXPLICIT`,
+	} {
+		if err := NewInterpreter().Interpret(line); err == nil {
+			t.Errorf("%s: expected a parse error", line)
+		}
+	}
+	bob, _ := newTestInterpreter(t, `10 OPTION BASE 1`)
+	if got, want := listing(t, bob), "10 OPTION BASE 1\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdey/gdbasic"
+)
+
+// load reads the program from name, using stdin for -, and runs it,
+// returning what it printed.
+func load(t *testing.T, name string, stdin string) string {
+	t.Helper()
+	src, err := openSource(name, strings.NewReader(stdin))
+	if err != nil {
+		t.Fatalf("openSource(%q): %v", name, err)
+	}
+	defer src.Close()
+	bob := gdbasic.NewInterpreter()
+	out := new(bytes.Buffer)
+	bob.Output = out
+	if err := bob.LoadReader(name, src); err != nil {
+		t.Fatalf("LoadReader: %v", err)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return out.String()
+}
+
+func TestOpenSourceStdin(t *testing.T) {
+	got := load(t, "-", "10 LET A=2\n20 PRINT \"PIPED\"; A*3\n")
+	if want := "PIPED6\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestOpenSourceFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "prog.bas")
+	if err := os.WriteFile(name, []byte("10 PRINT \"FILE\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// stdin is not read for a file
+	if got, want := load(t, name, "10 PRINT \"STDIN\"\n"), "FILE\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+	if _, err := openSource(filepath.Join(t.TempDir(), "missing.bas"), nil); err == nil {
+		t.Error("openSource of a missing file: expected an error")
+	}
+}
+
+// runCmd runs the command with args and stdin, returning the exit code and
+// what was written to stdout and stderr.
+func runCmd(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		program string
+		code    int
+		stdout  string
+		stderr  string
+	}{
+		{"valid", "20 GOTO 10\n10 PRINT \"RAN\"\n", 0, "10 PRINT\"RAN\"\n20 GOTO 10\n", ""},
+		{"dangling goto", "10 PRINT \"RAN\"\n20 GOTO 99\n", 1, "", "line 20 refers to missing line 99"},
+		{"parse error", "10 PRINT \"RAN\"\n20 LET A 5\n", 1, "", "missing `=`"},
+	}
+	for _, tt := range tests {
+		for _, flag := range []string{"-c", "--check", "-check"} {
+			t.Run(tt.name+flag, func(t *testing.T) {
+				code, stdout, stderr := runCmd(tt.program, flag, "-")
+				if code != tt.code {
+					t.Errorf("exit code %d, want %d", code, tt.code)
+				}
+				// only the listing is written, the program is not run
+				if stdout != tt.stdout {
+					t.Errorf("stdout is %q, want %q", stdout, tt.stdout)
+				}
+				if !strings.Contains(stderr, tt.stderr) || (tt.stderr == "" && stderr != "") {
+					t.Errorf("stderr is %q, want %q", stderr, tt.stderr)
+				}
+			})
+		}
+	}
+}
+
+func TestRunCmd(t *testing.T) {
+	const program = "10 LET A=1\n20 PRINT \"RAN\"\n"
+	code, stdout, _ := runCmd(program, "-q", "-")
+	if code != 0 || stdout != "RAN\n" {
+		t.Errorf("-q: exit code %d, stdout %q, want 0 and %q", code, stdout, "RAN\n")
+	}
+	// without -q the memory is dumped first
+	code, stdout, _ = runCmd(program, "-")
+	if code != 0 || !strings.HasSuffix(stdout, "RAN\n") || !strings.HasPrefix(stdout, "Instructions:\n10 LET A=1\n") {
+		t.Errorf("exit code %d, stdout %q, want the dump then RAN", code, stdout)
+	}
+	// a dangling GOTO is found before running, without -c as well
+	if code, stdout, _ := runCmd("10 PRINT \"RAN\"\n20 GOTO 99\n", "-q", "-"); code != 1 || stdout != "" {
+		t.Errorf("dangling GOTO: exit code %d, stdout %q, want 1 and nothing", code, stdout)
+	}
+	if code, _, _ := runCmd("10 PRINT 1/0\n", "-q", "-"); code != 1 {
+		t.Errorf("runtime error: exit code %d, want 1", code)
+	}
+	if code, _, stderr := runCmd(""); code != 1 || !strings.Contains(stderr, "need the basic file") {
+		t.Errorf("no file: exit code %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCmd("", "-x", "-"); code != 2 {
+		t.Errorf("bad flag: exit code %d, want 2", code)
+	}
+	if code, _, _ := runCmd("", filepath.Join(t.TempDir(), "missing.bas")); code != 1 {
+		t.Errorf("missing file: exit code %d, want 1", code)
+	}
+}
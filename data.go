@@ -0,0 +1,168 @@
+package gdbasic
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DataInstruction holds constants for READ, it does nothing when executed.
+type DataInstruction struct {
+	Values []Value
+	// text is each value as it was written, so that a number read into a
+	// string variable keeps its formatting.
+	text []string
+}
+
+func (DataInstruction) Execute(*Interpreter) error { return nil }
+
+// itemText returns value i as it was written.
+func (di DataInstruction) itemText(i int) string {
+	if i < len(di.text) {
+		return di.text[i]
+	}
+	return di.Values[i].String()
+}
+
+func (di DataInstruction) String() string {
+	items := make([]string, len(di.Values))
+	for i := range di.Values {
+		items[i] = di.itemText(i)
+	}
+	return "DATA " + strings.Join(items, ", ")
+}
+
+func NewDataInstruction(_ int, remainder string) (*DataInstruction, error) {
+	// DATA -1, 2.5, "x", y
+	di := new(DataInstruction)
+	for _, item := range splitOutsideQuotes(remainder, ",") {
+		item = strings.TrimSpace(item)
+		di.text = append(di.text, item)
+		if IsString(item) {
+			di.Values = append(di.Values, strValue(getString(item)))
+			continue
+		}
+		val, err := parseNumber(item)
+		if err != nil {
+			// unquoted strings are allowed as long as they are not numbers
+			val = strValue(item)
+		}
+		di.Values = append(di.Values, val)
+	}
+	return di, nil
+}
+
+// dataPointer is the position of the next DATA value to be read, as an
+// index into the instruction index and into that line's values.
+type dataPointer struct {
+	line int
+	item int
+}
+
+// ReadInstruction reads the next DATA values into variables. A number read
+// into a string variable becomes the string it was written as, so reading
+// `DATA 42` into A$ gives "42". It is an error to read a string into a
+// numeric variable.
+type ReadInstruction struct {
+	VarNames []string
+}
+
+func (ri ReadInstruction) Execute(intp *Interpreter) error {
+	// nothing is read if any of the variables is a constant
+	for _, name := range ri.VarNames {
+		if err := intp.checkWritable(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range ri.VarNames {
+		val, text, err := intp.readData()
+		if err != nil {
+			return err
+		}
+		switch {
+		case isStringVar(name) && !val.IsStr:
+			val = strValue(text)
+		case !isStringVar(name) && val.IsStr:
+			return fmt.Errorf("type mismatch reading %s into %s", val, name)
+		}
+		if err := intp.setVariable(name, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readData returns the next DATA value, and its text, and moves past it.
+func (bob *Interpreter) readData() (Value, string, error) {
+	for ; bob.data.line < len(bob.intructionIndex); bob.data.line, bob.data.item = bob.data.line+1, 0 {
+		di, ok := unlabeled(bob.Instructions[bob.intructionIndex[bob.data.line]]).(*DataInstruction)
+		if !ok || bob.data.item >= len(di.Values) {
+			continue
+		}
+		val := di.Values[bob.data.item]
+		bob.data.item++
+		return val, di.itemText(bob.data.item - 1), nil
+	}
+	return Value{}, "", fmt.Errorf("out of DATA")
+}
+
+func (ri ReadInstruction) String() string {
+	return "READ " + strings.Join(ri.VarNames, ", ")
+}
+
+func NewReadInstruction(_ int, remainder string) (*ReadInstruction, error) {
+	// READ A, B$
+	ri := new(ReadInstruction)
+	for _, name := range strings.Split(remainder, ",") {
+		name = varName(name)
+		if name == "" {
+			return nil, fmt.Errorf("read has a missing variable `%s`", remainder)
+		}
+		ri.VarNames = append(ri.VarNames, name)
+	}
+	return ri, nil
+}
+
+// RestoreInstruction makes the next READ start from the first DATA at or
+// after Line, or from the first DATA in the program if Line is 0.
+type RestoreInstruction struct {
+	Line int
+}
+
+func (ri RestoreInstruction) Execute(intp *Interpreter) error {
+	intp.data = dataPointer{line: sort.SearchInts(intp.intructionIndex, ri.Line)}
+	return nil
+}
+
+func (ri RestoreInstruction) String() string {
+	if ri.Line == 0 {
+		return "RESTORE"
+	}
+	return fmt.Sprintf("RESTORE %d", ri.Line)
+}
+
+func (ri RestoreInstruction) LineRefs() []int {
+	if ri.Line == 0 {
+		return nil
+	}
+	return []int{ri.Line}
+}
+
+func (ri RestoreInstruction) RenumberRefs(lines map[int]int) Instructioner {
+	if ri.Line == 0 {
+		return ri
+	}
+	return RestoreInstruction{Line: lines[ri.Line]}
+}
+
+func NewRestoreInstruction(_ int, remainder string) (RestoreInstruction, error) {
+	if remainder == "" {
+		return RestoreInstruction{}, nil
+	}
+	i64, err := strconv.ParseInt(remainder, 10, 32)
+	if err != nil {
+		return RestoreInstruction{}, fmt.Errorf("restore has a bad line number `%s`: %v", remainder, err)
+	}
+	return RestoreInstruction{Line: int(i64)}, nil
+}
@@ -0,0 +1,72 @@
+package gdbasic
+
+import "testing"
+
+func TestDataTypes(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`10 READ A, B, C$, D$, E, F`,
+		`20 DATA -1, 2.5, "x", HELLO WORLD`,
+		`30 DATA &HFF, -1.5E2`,
+	)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	tests := []struct {
+		name string
+		want Value
+	}{
+		{"A", Value{Int: -1}},
+		{"B", floatValue(2.5)},
+		{"C$", strValue("x")},
+		{"D$", strValue("HELLO WORLD")},
+		{"E", Value{Int: 255}},
+		{"F", floatValue(-150)},
+	}
+	for _, tc := range tests {
+		if got := bob.Variables[tc.name]; got != tc.want {
+			t.Errorf("%s = %#v, want %#v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDataParse(t *testing.T) {
+	ins, err := parseInstruction(10, `DATA -1, 2.5, "x", y`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	di := ins.(*DataInstruction)
+	want := []Value{{Int: -1}, floatValue(2.5), strValue("x"), strValue("y")}
+	if len(di.Values) != len(want) {
+		t.Fatalf("got values %v, want %v", di.Values, want)
+	}
+	for i := range want {
+		if di.Values[i] != want[i] {
+			t.Errorf("value %d = %#v, want %#v", i, di.Values[i], want[i])
+		}
+	}
+	if got, want := di.String(), `DATA -1, 2.5, "x", y`; got != want {
+		t.Errorf("listed as %q, want %q", got, want)
+	}
+}
+func TestReadCoercion(t *testing.T) {
+	got := runProgram(t,
+		`10 READ A$, B$`,
+		`20 PRINT "["; A$; "]["; B$; "]"`,
+		`30 DATA 007, 2.50`,
+	)
+	// a number read into a string keeps the text it was written as
+	if want := "[007][2.50]\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+
+	for _, program := range [][]string{
+		{`10 READ A`, `20 DATA "X"`},
+		{`10 READ A`, `20 DATA WORD`},
+		{`10 READ A, B`, `20 DATA 1`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		if err := bob.Run(); err == nil {
+			t.Errorf("%q: expected an error", program)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package gdbasic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UserFunction is a single line function defined with DEF FN.
+type UserFunction struct {
+	Params []string
+	Body   Expression
+}
+
+// call evaluates the body of the function with the parameters bound to the
+// args. The parameters shadow any variables of the same name, which are put
+// back afterwards.
+func (uf UserFunction) call(intp *Interpreter, name string, args []Value) (Value, error) {
+	if len(args) != len(uf.Params) {
+		return Value{}, fmt.Errorf("%s: expected %d arguments got %d", name, len(uf.Params), len(args))
+	}
+	if intp.MaxCallDepth > 0 && intp.fnDepth >= intp.MaxCallDepth {
+		return Value{}, fmt.Errorf("out of memory: %s nested more than %d deep", name, intp.MaxCallDepth)
+	}
+	intp.fnDepth++
+	defer func() { intp.fnDepth-- }()
+	type saved struct {
+		val Value
+		ok  bool
+	}
+	shadowed := make([]saved, len(uf.Params))
+	intp.lockVars()
+	for i, param := range uf.Params {
+		shadowed[i].val, shadowed[i].ok = intp.Variables[param]
+		intp.Variables[param] = args[i]
+	}
+	intp.unlockVars()
+	defer func() {
+		intp.lockVars()
+		defer intp.unlockVars()
+		for i, param := range uf.Params {
+			if shadowed[i].ok {
+				intp.Variables[param] = shadowed[i].val
+			} else {
+				delete(intp.Variables, param)
+			}
+		}
+	}()
+	return uf.Body.Eval(intp)
+}
+
+// DefFnInstruction defines a user function, such as `DEF FNSQ(X)=X*X`.
+type DefFnInstruction struct {
+	Name string
+	UserFunction
+}
+
+func (dfi DefFnInstruction) Execute(intp *Interpreter) error {
+	intp.Functions[dfi.Name] = dfi.UserFunction
+	return nil
+}
+
+func (dfi DefFnInstruction) String() string {
+	if len(dfi.Params) == 0 {
+		return fmt.Sprintf("DEF %s=%s", dfi.Name, dfi.Body)
+	}
+	return fmt.Sprintf("DEF %s(%s)=%s", dfi.Name, strings.Join(dfi.Params, ","), dfi.Body)
+}
+
+func NewDefFnInstruction(_ int, remainder string) (*DefFnInstruction, error) {
+	// DEF FNSQ(X) = X*X
+	idx := strings.Index(remainder, "=")
+	if idx == -1 {
+		return nil, fmt.Errorf("invalid def statement")
+	}
+	head := strings.TrimSpace(remainder[:idx])
+	dfi := new(DefFnInstruction)
+	if open := strings.IndexByte(head, '('); open != -1 {
+		if !strings.HasSuffix(head, ")") {
+			return nil, fmt.Errorf("def: missing `)`")
+		}
+		for _, param := range strings.Split(head[open+1:len(head)-1], ",") {
+			param = varName(param)
+			if param == "" {
+				return nil, fmt.Errorf("def: empty parameter name")
+			}
+			dfi.Params = append(dfi.Params, param)
+		}
+		head = head[:open]
+	}
+	dfi.Name = strings.ToUpper(strings.ReplaceAll(head, " ", ""))
+	if !strings.HasPrefix(dfi.Name, "FN") || len(dfi.Name) == 2 {
+		return nil, fmt.Errorf("def: function name `%s` must start with FN", dfi.Name)
+	}
+	body, err := ParseExpression(remainder[idx+1:])
+	if err != nil {
+		return nil, err
+	}
+	dfi.Body = body
+	return dfi, nil
+}
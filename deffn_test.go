@@ -0,0 +1,124 @@
+package gdbasic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefFn(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{
+			name:    "one parameter",
+			program: []string{`10 DEF FNSQ(X)=X*X`, `20 PRINT FNSQ(7)`},
+			want:    "49\n",
+		},
+		{
+			name:    "several parameters",
+			program: []string{`10 DEF FNHYP(A, B)=A*A+B*B`, `20 PRINT FNHYP(3, 4)`},
+			want:    "25\n",
+		},
+		{
+			name:    "no parameters",
+			program: []string{`10 LET R=2`, `20 DEF FNAREA=3*R*R`, `30 PRINT FNAREA`},
+			want:    "12\n",
+		},
+		{
+			name:    "string function",
+			program: []string{`10 DEF FNTWICE$(S$)=S$+S$`, `20 PRINT FNTWICE$("AB")`},
+			want:    "ABAB\n",
+		},
+		{
+			name:    "parameters shadow variables",
+			program: []string{`10 LET X=5`, `20 DEF FNINC(X)=X+1`, `30 PRINT FNINC(10); X`},
+			want:    "115\n",
+		},
+		{
+			name:    "calls another function",
+			program: []string{`10 DEF FNSQ(X)=X*X`, `20 DEF FNQUAD(X)=FNSQ(FNSQ(X))`, `30 PRINT FNQUAD(2)`},
+			want:    "16\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runProgram(t, tc.program...); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefFnParameterIsNotLeft(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 DEF FNSQ(Q)=Q*Q`, `20 LET A=FNSQ(3)`)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, ok := bob.Variables["Q"]; ok {
+		t.Error("the parameter Q was left behind as a variable")
+	}
+}
+
+func TestDefFnErrors(t *testing.T) {
+	for _, program := range [][]string{
+		{`10 PRINT FNNOPE(1)`},
+		{`10 DEF FNSQ(X)=X*X`, `20 PRINT FNSQ(1, 2)`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		if err := bob.Run(); err == nil {
+			t.Errorf("%q: expected an error", program)
+		}
+	}
+	for _, line := range []string{
+		`DEF SQ(X)=X*X`,
+		`DEF FNSQ(X`,
+		`DEF FNSQ(X, )=X`,
+		`DEF FNSQ(X)`,
+	} {
+		if _, err := parseInstruction(10, line); err == nil {
+			t.Errorf("%s: expected an error", line)
+		}
+	}
+}
+
+func TestDefFnRecursion(t *testing.T) {
+	for _, depth := range []int{1, 10, defaultMaxCallDepth} {
+		bob, _ := newTestInterpreter(t,
+			`10 DEF FNA(X)=FNA(X)+1`,
+			`20 PRINT FNA(1)`,
+		)
+		bob.MaxCallDepth = depth
+		// all the calls happen in one step, so the step limit can't stop them
+		err := bob.RunWithLimit(10)
+		if err == nil || !strings.Contains(err.Error(), "out of memory") {
+			t.Errorf("depth %d: Run() = %v, want out of memory", depth, err)
+		}
+		if bob.fnDepth != 0 {
+			t.Errorf("depth %d: %d calls left open after the error", depth, bob.fnDepth)
+		}
+	}
+
+	// as nothing short-circuits, a DEF FN that calls itself never stops, but
+	// nested calls of different functions are fine up to the limit
+	for _, tt := range []struct {
+		max int
+		ok  bool
+	}{{0, true}, {3, true}, {2, false}} {
+		bob, out := newTestInterpreter(t,
+			`10 DEF FNA(X)=X+1`,
+			`20 DEF FNB(X)=FNA(X)*2`,
+			`30 DEF FNC(X)=FNB(X)+FNA(X)`,
+			`40 PRINT FNC(1)`,
+		)
+		bob.MaxCallDepth = tt.max
+		err := bob.Run()
+		if tt.ok && (err != nil || out.String() != "6\n") {
+			t.Errorf("MaxCallDepth %d: Run() = %v, printed %q, want 6", tt.max, err, out.String())
+		}
+		if !tt.ok && (err == nil || !strings.Contains(err.Error(), "out of memory")) {
+			t.Errorf("MaxCallDepth %d: Run() = %v, want out of memory", tt.max, err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package gdbasic
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Renumber reassigns the line numbers of the program starting at start and
+// incrementing by step, updating every reference to a line (such as GOTO
+// targets) to match. It is an error for a reference to point at a line that
+// does not exist, as it could not be renumbered.
+func (bob *Interpreter) Renumber(start, step int) error {
+	if bob.Frozen {
+		return fmt.Errorf("renumber: %w", ErrFrozen)
+	}
+	if start < 0 || step <= 0 {
+		return fmt.Errorf("renumber: bad start %v or step %v", start, step)
+	}
+	bob.invalidate()
+	if err := bob.buildInstructionIndex(); err != nil {
+		return err
+	}
+	lines := make(map[int]int, len(bob.intructionIndex))
+	for i, ln := range bob.intructionIndex {
+		lines[ln] = start + i*step
+	}
+	for _, ln := range bob.intructionIndex {
+		lr, ok := bob.Instructions[ln].(LineReferencer)
+		if !ok {
+			continue
+		}
+		for _, ref := range lr.LineRefs() {
+			if _, ok := lines[ref]; !ok {
+				return fmt.Errorf("renumber: line %v refers to missing line %v", ln, ref)
+			}
+		}
+	}
+
+	bob.moveLines(lines)
+	return nil
+}
+
+// moveLines moves every line to the number lines maps it to, updating the
+// references to them. lines must map every line and every reference.
+func (bob *Interpreter) moveLines(lines map[int]int) {
+	instructions := make(map[int]Instructioner, len(bob.Instructions))
+	source := make(map[int]string, len(bob.source))
+	for ln, ins := range bob.Instructions {
+		lr, refs := ins.(LineReferencer)
+		if refs {
+			ins = lr.RenumberRefs(lines)
+		}
+		instructions[lines[ln]] = ins
+		// The kept source has the old line numbers in it.
+		if raw, ok := bob.source[ln]; ok && !refs && lines[ln] == ln {
+			source[ln] = raw
+		}
+	}
+	bob.Instructions = instructions
+	bob.source = source
+	bob.invalidate()
+
+	if bob.breakpoints != nil {
+		breakpoints := make(map[int]bool, len(bob.breakpoints))
+		for ln := range bob.breakpoints {
+			if newLn, ok := lines[ln]; ok {
+				breakpoints[newLn] = true
+			}
+		}
+		bob.breakpoints = breakpoints
+	}
+}
+
+// maxInsertLine is the highest line number Insert will move a line to, the
+// highest line number GW-BASIC allows.
+const maxInsertLine = 65529
+
+// Insert makes room for a new line straight after the line after, returning
+// the number the new line should be given. If the next line number is already
+// taken the lines that follow are moved up by one, only as many as needed to
+// reach a gap, and references to them are updated. When there is no room
+// left the program has to be renumbered with Renumber.
+func (bob *Interpreter) Insert(after int) (int, error) {
+	if bob.Frozen {
+		return 0, fmt.Errorf("insert: %w", ErrFrozen)
+	}
+	if _, ok := bob.Instructions[after+1]; !ok {
+		return after + 1, nil
+	}
+	end := after + 1
+	for {
+		if _, ok := bob.Instructions[end+1]; !ok {
+			break
+		}
+		end++
+	}
+	if end+1 > maxInsertLine {
+		return 0, fmt.Errorf("insert: no room after line %v, renumber the program", after)
+	}
+
+	lines := make(map[int]int, len(bob.Instructions))
+	for ln, ins := range bob.Instructions {
+		lines[ln] = ln
+		if lr, ok := ins.(LineReferencer); ok {
+			for _, ref := range lr.LineRefs() {
+				lines[ref] = ref
+			}
+		}
+	}
+	for ln := after + 1; ln <= end; ln++ {
+		lines[ln] = ln + 1
+	}
+	bob.moveLines(lines)
+	return after + 1, nil
+}
+
+// Validate checks that every line referred to by the program, such as a GOTO
+// target, exists, as does every label gone to. All the missing lines are
+// reported, not just the first.
+func (bob *Interpreter) Validate() error {
+	if err := bob.buildInstructionIndex(); err != nil {
+		return err
+	}
+	lines := make([]int, 0, len(bob.Instructions))
+	for ln := range bob.Instructions {
+		lines = append(lines, ln)
+	}
+	sort.Ints(lines)
+
+	var errs []error
+	for _, ln := range lines {
+		for _, label := range labelRefs(bob.Instructions[ln]) {
+			if _, ok := bob.labels[label]; !ok {
+				errs = append(errs, fmt.Errorf("line %v refers to missing label %v", ln, label))
+			}
+		}
+		lr, ok := bob.Instructions[ln].(LineReferencer)
+		if !ok {
+			continue
+		}
+		for _, ref := range lr.LineRefs() {
+			if _, ok := bob.Instructions[ref]; !ok {
+				errs = append(errs, fmt.Errorf("line %v refers to missing line %v", ln, ref))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Delete removes the lines numbered from to to, inclusive, and returns how
+// many were removed. Breakpoints on the removed lines are cleared. Any GOTO
+// to a removed line is left as it is, Validate will report it. Nothing is
+// removed from a Frozen program.
+func (bob *Interpreter) Delete(from, to int) int {
+	if bob.Frozen {
+		return 0
+	}
+	removed := 0
+	for ln := range bob.Instructions {
+		if ln < from || ln > to {
+			continue
+		}
+		delete(bob.Instructions, ln)
+		delete(bob.source, ln)
+		delete(bob.breakpoints, ln)
+		removed++
+	}
+	if removed > 0 {
+		bob.invalidate()
+	}
+	return removed
+}
+
+// List writes the program to w a line at a time. Lines kept by KeepSource
+// are written exactly as they were typed, others as the interpreter
+// understood them.
+func (bob *Interpreter) List(w io.Writer) error {
+	if err := bob.buildInstructionIndex(); err != nil {
+		return err
+	}
+	var buf strings.Builder
+	for _, ln := range bob.intructionIndex {
+		if raw, ok := bob.source[ln]; ok {
+			buf.WriteString(raw)
+		} else {
+			fmt.Fprintf(&buf, "%d %s", ln, bob.Instructions[ln])
+		}
+		buf.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// Each calls fn for each line of the program in line number order, stopping
+// at the first error fn returns, which is returned.
+func (bob *Interpreter) Each(fn func(line int, ins Instructioner) error) error {
+	if err := bob.buildInstructionIndex(); err != nil {
+		return err
+	}
+	for _, ln := range bob.intructionIndex {
+		if err := fn(ln, bob.Instructions[ln]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,436 @@
+package gdbasic
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// listing returns the program as List writes it.
+func listing(t *testing.T, bob *Interpreter) string {
+	t.Helper()
+	var buf strings.Builder
+	if err := bob.List(&buf); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	return buf.String()
+}
+
+func TestRenumber(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`1 LET A=0`,
+		`2 GOSUB 7`,
+		`3 IF A<3 THEN 2 ELSE 5`,
+		`5 GOTO 9`,
+		`7 LET A=A+1`,
+		`8 RETURN`,
+		`9 PRINT A`,
+	)
+	if err := bob.Renumber(100, 10); err != nil {
+		t.Fatalf("Renumber: %v", err)
+	}
+	want := strings.Join([]string{
+		`100 LET A=0`,
+		`110 GOSUB 140`,
+		`120 IF A<3 THEN 110 ELSE 130`,
+		`130 GOTO 160`,
+		`140 LET A=A+1`,
+		`150 RETURN`,
+		`160 PRINT A`,
+	}, "\n") + "\n"
+	if got := listing(t, bob); got != want {
+		t.Errorf("listing after Renumber:\n%s\nwant:\n%s", got, want)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := out.String(); got != "3\n" {
+		t.Errorf("renumbered program printed %q, want %q", got, "3\n")
+	}
+}
+
+func TestRenumberOtherReferences(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`5 ON ERROR GOTO 8`,
+		`6 RESTORE 9`,
+		`8 RESUME 6`,
+		`9 DATA 1`,
+	)
+	if err := bob.Renumber(10, 10); err != nil {
+		t.Fatalf("Renumber: %v", err)
+	}
+	for _, want := range []string{`10 ON ERROR GOTO 30`, `20 RESTORE 40`, `30 RESUME 20`} {
+		if got := listing(t, bob); !strings.Contains(got, want+"\n") {
+			t.Errorf("listing is missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenumberErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		program     []string
+		start, step int
+	}{
+		{"missing target", []string{`10 GOTO 99`}, 100, 10},
+		{"zero step", []string{`10 PRINT`}, 100, 0},
+		{"negative start", []string{`10 PRINT`}, -1, 10},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tc.program...)
+			before := listing(t, bob)
+			if err := bob.Renumber(tc.start, tc.step); err == nil {
+				t.Fatal("expected an error")
+			}
+			if after := listing(t, bob); after != before {
+				t.Errorf("program changed by a failed Renumber:\n%s", after)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		missing []string
+	}{
+		{
+			name:    "valid",
+			program: []string{`10 GOSUB 30`, `20 GOTO 40`, `30 RETURN`, `40 IF A=1 THEN 10 ELSE 20`},
+		},
+		{
+			name:    "goto",
+			program: []string{`10 GOTO 99`},
+			missing: []string{"line 10 refers to missing line 99"},
+		},
+		{
+			name: "every missing line is reported",
+			program: []string{
+				`10 GOSUB 98`,
+				`20 IF A=1 THEN 97 ELSE 96`,
+				`30 RESTORE 95`,
+				`40 GOTO 10`,
+			},
+			missing: []string{
+				"line 10 refers to missing line 98",
+				"line 20 refers to missing line 97",
+				"line 20 refers to missing line 96",
+				"line 30 refers to missing line 95",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tc.program...)
+			err := bob.Validate()
+			if len(tc.missing) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, msg := range tc.missing {
+				if !strings.Contains(err.Error(), msg) {
+					t.Errorf("error %q does not report %q", err, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	program := []string{`10 PRINT "A"`, `20 PRINT "B"`, `30 PRINT "C"`, `40 PRINT "D"`}
+	tests := []struct {
+		name     string
+		from, to int
+		removed  int
+		want     string
+	}{
+		{"single line", 20, 20, 1, "A\nC\nD\n"},
+		{"range", 20, 30, 2, "A\nD\n"},
+		{"range between lines", 15, 35, 2, "A\nD\n"},
+		{"everything", 0, 100, 4, ""},
+		{"nothing there", 21, 29, 0, "A\nB\nC\nD\n"},
+		{"backwards range", 30, 20, 0, "A\nB\nC\nD\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t, program...)
+			// build the index first, so the Delete has to invalidate it
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			out.Reset()
+			if got := bob.Delete(tc.from, tc.to); got != tc.removed {
+				t.Errorf("Delete(%d, %d) = %d, want %d", tc.from, tc.to, got, tc.removed)
+			}
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run after Delete: %v", err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("printed %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeleteGotoTarget(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`10 GOTO 30`,
+		`20 PRINT "SKIPPED"`,
+		`30 PRINT "TARGET"`,
+	)
+	bob.SetBreakpoint(30)
+	if got := bob.Delete(30, 30); got != 1 {
+		t.Fatalf("Delete(30, 30) = %d, want 1", got)
+	}
+	if err := bob.Validate(); err == nil || !strings.Contains(err.Error(), "line 10 refers to missing line 30") {
+		t.Errorf("Validate() = %v, want the GOTO to the deleted line reported", err)
+	}
+	// the GOTO is an error at run time, not a stop at the deleted breakpoint
+	if err := bob.Run(); err == nil || err == ErrBreakpoint {
+		t.Errorf("Run() = %v, want a missing line error", err)
+	}
+	if got := listing(t, bob); got != "10 GOTO 30\n20 PRINT\"SKIPPED\"\n" {
+		t.Errorf("listing after Delete:\n%s", got)
+	}
+}
+
+func TestDeleteFrozen(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 PRINT "A"`)
+	bob.Frozen = true
+	if got := bob.Delete(10, 10); got != 0 {
+		t.Errorf("Delete on a Frozen program = %d, want 0", got)
+	}
+	if got := listing(t, bob); got != "10 PRINT\"A\"\n" {
+		t.Errorf("listing after Delete:\n%s", got)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A=0`,
+		`11 GOSUB 13`,
+		`12 IF A<3 THEN 11 ELSE 20`,
+		`13 LET A=A+1`,
+		`14 RETURN`,
+		`20 PRINT A`,
+	)
+	ln, err := bob.Insert(10)
+	if err != nil {
+		t.Fatalf("Insert(10): %v", err)
+	}
+	if ln != 11 {
+		t.Errorf("Insert(10) = %d, want 11", ln)
+	}
+	// only lines up to the gap at 15 are moved
+	want := strings.Join([]string{
+		`10 LET A=0`,
+		`12 GOSUB 14`,
+		`13 IF A<3 THEN 12 ELSE 20`,
+		`14 LET A=A+1`,
+		`15 RETURN`,
+		`20 PRINT A`,
+	}, "\n") + "\n"
+	if got := listing(t, bob); got != want {
+		t.Errorf("listing after Insert:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := bob.Interpret(`11 LET A=1`); err != nil {
+		t.Fatalf("Interpret: %v", err)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := out.String(); got != "3\n" {
+		t.Errorf("printed %q, want %q", got, "3\n")
+	}
+}
+
+func TestInsertGap(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 PRINT "A"`, `20 PRINT "B"`)
+	before := listing(t, bob)
+	ln, err := bob.Insert(10)
+	if err != nil || ln != 11 {
+		t.Errorf("Insert(10) = %d, %v, want 11, nil", ln, err)
+	}
+	if after := listing(t, bob); after != before {
+		t.Errorf("program changed when there was a gap:\n%s", after)
+	}
+}
+
+func TestInsertNoRoom(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `65528 PRINT "A"`, `65529 PRINT "B"`)
+	before := listing(t, bob)
+	if ln, err := bob.Insert(65527); err == nil {
+		t.Errorf("Insert(65527) = %d, expected an error", ln)
+	}
+	if after := listing(t, bob); after != before {
+		t.Errorf("program changed by a failed Insert:\n%s", after)
+	}
+
+	bob.Frozen = true
+	if _, err := bob.Insert(1); err == nil {
+		t.Error("Insert on a Frozen program: expected an error")
+	}
+}
+
+func TestKeepSource(t *testing.T) {
+	program := []string{
+		`10 PRINT   "HI" ;  A`,
+		`20 LET B = 1 + 2   `,
+		`30 GOTO 10`,
+	}
+	tests := []struct {
+		keep bool
+		want string
+	}{
+		{true, strings.Join(program, "\n") + "\n"},
+		{false, "10 PRINT\"HI\";A\n20 LET B=1+2\n30 GOTO 10\n"},
+	}
+	for _, tc := range tests {
+		bob := NewInterpreter()
+		bob.KeepSource = tc.keep
+		for _, line := range program {
+			if err := bob.Interpret(line); err != nil {
+				t.Fatalf("Interpret(%q): %v", line, err)
+			}
+		}
+		if got := listing(t, bob); got != tc.want {
+			t.Errorf("KeepSource %v listed:\n%s\nwant:\n%s", tc.keep, got, tc.want)
+		}
+	}
+}
+
+func TestKeepSourceEdits(t *testing.T) {
+	bob := NewInterpreter()
+	bob.KeepSource = true
+	for _, line := range []string{`10 PRINT  "A"`, `20 GOTO 10`, `30 PRINT  "C"`} {
+		if err := bob.Interpret(line); err != nil {
+			t.Fatalf("Interpret(%q): %v", line, err)
+		}
+	}
+	// a line typed again replaces its kept source
+	if err := bob.Interpret(`30 PRINT  "D"`); err != nil {
+		t.Fatalf("Interpret: %v", err)
+	}
+	if err := bob.Renumber(100, 100); err != nil {
+		t.Fatalf("Renumber: %v", err)
+	}
+	// lines that moved, or refer to lines that moved, are listed as understood
+	want := "100 PRINT\"A\"\n200 GOTO 100\n300 PRINT\"D\"\n"
+	if got := listing(t, bob); got != want {
+		t.Errorf("listed:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFrozen(t *testing.T) {
+	edits := []struct {
+		name string
+		edit func(bob *Interpreter) error
+	}{
+		{"new line", func(bob *Interpreter) error { return bob.Interpret(`30 PRINT "C"`) }},
+		{"replaced line", func(bob *Interpreter) error { return bob.Interpret(`10 PRINT "X"`) }},
+		{"deleted line", func(bob *Interpreter) error { return bob.Interpret(`10`) }},
+		{"direct mode line", func(bob *Interpreter) error { return bob.Execute(`30 PRINT "C"`) }},
+		{"load", func(bob *Interpreter) error {
+			return bob.LoadReader("PROG.BAS", strings.NewReader("30 PRINT \"C\"\n"))
+		}},
+		{"renumber", func(bob *Interpreter) error { return bob.Renumber(100, 10) }},
+		{"insert", func(bob *Interpreter) error {
+			_, err := bob.Insert(10)
+			return err
+		}},
+	}
+	for _, tc := range edits {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t, `10 PRINT "A"`, `11 PRINT "B"`)
+			before := listing(t, bob)
+			bob.Frozen = true
+			if err := tc.edit(bob); !errors.Is(err, ErrFrozen) {
+				t.Errorf("got error %v, want ErrFrozen", err)
+			}
+			if after := listing(t, bob); after != before {
+				t.Errorf("program changed while frozen:\n%s", after)
+			}
+			// a frozen program still runs, and direct mode still works
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if err := bob.Execute(`PRINT "D"`); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if got := out.String(); got != "A\nB\nD\n" {
+				t.Errorf("printed %q", got)
+			}
+		})
+	}
+}
+
+func TestEach(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`30 PRINT 3`,
+		`10 PRINT 1`,
+		`50 PRINT 5`,
+		`20 PRINT 2`,
+	)
+	var lines []int
+	var texts []string
+	err := bob.Each(func(line int, ins Instructioner) error {
+		lines = append(lines, line)
+		texts = append(texts, ins.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each: %v", err)
+	}
+	if want := []int{10, 20, 30, 50}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("Each saw lines %v, want %v", lines, want)
+	}
+	if want := []string{"PRINT 1", "PRINT 2", "PRINT 3", "PRINT 5"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("Each saw %q, want %q", texts, want)
+	}
+
+	// lines added after a walk are seen by the next one
+	if err := bob.Interpret(`15 PRINT 15`); err != nil {
+		t.Fatal(err)
+	}
+	lines = nil
+	bob.Each(func(line int, _ Instructioner) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if want := []int{10, 15, 20, 30, 50}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("Each saw lines %v after adding 15, want %v", lines, want)
+	}
+}
+
+func TestEachStops(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 PRINT 1`, `20 PRINT 2`, `30 PRINT 3`)
+	stop := errors.New("stop")
+	var lines []int
+	err := bob.Each(func(line int, _ Instructioner) error {
+		lines = append(lines, line)
+		if line == 20 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Each returned %v, want %v", err, stop)
+	}
+	if want := []int{10, 20}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("Each saw lines %v, want %v", lines, want)
+	}
+	// an empty program calls fn no times
+	calls := 0
+	if err := NewInterpreter().Each(func(int, Instructioner) error { calls++; return nil }); err != nil || calls != 0 {
+		t.Errorf("Each of an empty program: %v with %d calls", err, calls)
+	}
+}
@@ -0,0 +1,19 @@
+package gdbasic_test
+
+import "github.com/gdey/gdbasic"
+
+func Example() {
+	bob := gdbasic.NewInterpreter()
+	for _, line := range []string{
+		`10 LET A=6*7`,
+		`20 PRINT "THE ANSWER IS "; A`,
+	} {
+		if err := bob.Interpret(line); err != nil {
+			panic(err)
+		}
+	}
+	if err := bob.Run(); err != nil {
+		panic(err)
+	}
+	// Output: THE ANSWER IS 42
+}
@@ -0,0 +1,687 @@
+package gdbasic
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Expression is anything that can be evaluated to a Value.
+type Expression interface {
+	fmt.Stringer
+	Eval(*Interpreter) (Value, error)
+}
+
+func (v Value) Eval(*Interpreter) (Value, error) { return v, nil }
+
+func (ref Reference) Eval(intp *Interpreter) (Value, error) {
+	return intp.variable(string(ref))
+}
+
+// ParenExpression is an expression that was wrapped in parentheses, it is kept
+// so the listing matches what was typed.
+type ParenExpression struct {
+	Expr Expression
+}
+
+func (pe ParenExpression) Eval(intp *Interpreter) (Value, error) { return pe.Expr.Eval(intp) }
+func (pe ParenExpression) String() string                        { return fmt.Sprintf("(%s)", pe.Expr) }
+
+// FuncExpression is a call to a built in function such as `FORMAT$(A,"##.#")`.
+type FuncExpression struct {
+	Name string
+	Args []Expression
+}
+
+func (fe FuncExpression) String() string {
+	if len(fe.Args) == 0 {
+		return fe.Name
+	}
+	args := make([]string, len(fe.Args))
+	for i := range fe.Args {
+		args[i] = fe.Args[i].String()
+	}
+	return fmt.Sprintf("%s(%s)", fe.Name, strings.Join(args, ","))
+}
+
+func (fe FuncExpression) Eval(intp *Interpreter) (Value, error) {
+	if fn, ok := refBuiltins[fe.Name]; ok {
+		return fn(intp, fe.Args)
+	}
+	args := make([]Value, len(fe.Args))
+	for i := range fe.Args {
+		var err error
+		if args[i], err = fe.Args[i].Eval(intp); err != nil {
+			return Value{}, err
+		}
+	}
+	if strings.HasPrefix(fe.Name, "FN") {
+		fn, ok := intp.Functions[fe.Name]
+		if !ok {
+			return Value{}, fmt.Errorf("undefined user function: %s", fe.Name)
+		}
+		return fn.call(intp, fe.Name, args)
+	}
+	if slot, ok := usrSlot(fe.Name); ok {
+		return intp.callUsr(slot, args)
+	}
+	if fn, ok := builtins[fe.Name]; ok {
+		return fn(intp, args)
+	}
+	if fn, ok := intp.hostFuncs[fe.Name]; ok {
+		return fn(intp, args)
+	}
+	if len(args) > 0 {
+		// anything else with subscripts is an array
+		return intp.arrayElement(fe.Name, args)
+	}
+	return Value{}, fmt.Errorf("unknown function: %s", fe.Name)
+}
+
+// ChannelExpression is a file number written with a leading #, as in
+// INPUT$(5, #1). It evaluates to the number.
+type ChannelExpression struct {
+	Expr Expression
+}
+
+func (ce ChannelExpression) String() string { return "#" + ce.Expr.String() }
+
+func (ce ChannelExpression) Eval(intp *Interpreter) (Value, error) {
+	return ce.Expr.Eval(intp)
+}
+
+// UnaryExpression is an operator applied to a single operand.
+type UnaryExpression struct {
+	Op   string
+	Expr Expression
+}
+
+func (ue UnaryExpression) String() string {
+	if ue.Op == "-" || ue.Op == "+" {
+		return ue.Op + ue.Expr.String()
+	}
+	return fmt.Sprintf("%s %s", ue.Op, ue.Expr)
+}
+
+// Eval applies the operator. - and + are the sign of a number. NOT is
+// bitwise, as are AND, OR and XOR, floats being truncated to integers
+// first. They work as logical operators because true is -1, all bits set,
+// and false is 0.
+func (ue UnaryExpression) Eval(intp *Interpreter) (Value, error) {
+	val, err := ue.Expr.Eval(intp)
+	if err != nil {
+		return Value{}, err
+	}
+	if val.IsStr {
+		return Value{}, fmt.Errorf("type mismatch: %s", ue)
+	}
+	switch ue.Op {
+	case "NOT":
+		return Value{Int: ^val.int()}, nil
+	case "-":
+		return negate(val), nil
+	case "+":
+		return val, nil
+	}
+	return Value{}, fmt.Errorf("unknown operator `%s`", ue.Op)
+}
+
+// negate returns the number v with its sign flipped.
+func negate(v Value) Value {
+	if v.IsFloat {
+		v.Float = -v.Float
+	} else {
+		v.Int = -v.Int
+	}
+	return v
+}
+
+type BinaryExpression struct {
+	Op    string
+	Left  Expression
+	Right Expression
+}
+
+func (be BinaryExpression) String() string {
+	switch be.Op {
+	case "MOD", "AND", "OR", "XOR":
+		return fmt.Sprintf("%s %s %s", be.Left, be.Op, be.Right)
+	}
+	return fmt.Sprintf("%s%s%s", be.Left, be.Op, be.Right)
+}
+
+func (be BinaryExpression) Eval(intp *Interpreter) (Value, error) {
+	left, err := be.Left.Eval(intp)
+	if err != nil {
+		return Value{}, err
+	}
+	right, err := be.Right.Eval(intp)
+	if err != nil {
+		return Value{}, err
+	}
+	switch be.Op {
+	case "=", "<>", "<", ">", "<=", ">=":
+		cmp, err := left.Compare(right)
+		if err != nil {
+			return Value{}, fmt.Errorf("%v: %s", err, be)
+		}
+		return boolValue(compares(be.Op, cmp)), nil
+	}
+	if left.IsStr || right.IsStr {
+		if be.Op == "+" && left.IsStr && right.IsStr {
+			return strValue(left.Str + right.Str), nil
+		}
+		return Value{}, fmt.Errorf("type mismatch: %s", be)
+	}
+
+	switch be.Op {
+	case "+", "-", "*":
+		if !left.IsFloat && !right.IsFloat {
+			switch be.Op {
+			case "+":
+				return intp.intValue(left.Int+right.Int, be)
+			case "-":
+				return intp.intValue(left.Int-right.Int, be)
+			default:
+				return intp.intValue(left.Int*right.Int, be)
+			}
+		}
+		l, r := left.float(), right.float()
+		switch be.Op {
+		case "+":
+			return floatValue(l + r), nil
+		case "-":
+			return floatValue(l - r), nil
+		default:
+			return floatValue(l * r), nil
+		}
+	case "/":
+		if right.float() == 0 {
+			return intp.divisionByZero(be)
+		}
+		return floatValue(left.float() / right.float()), nil
+	case "^":
+		if left.float() == 0 && right.float() < 0 {
+			return intp.divisionByZero(be)
+		}
+		p := math.Pow(left.float(), right.float())
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			return Value{}, fmt.Errorf("illegal function call: %s", be)
+		}
+		return floatValue(p), nil
+	case "AND":
+		return Value{Int: left.int() & right.int()}, nil
+	case "OR":
+		return Value{Int: left.int() | right.int()}, nil
+	case "XOR":
+		return Value{Int: left.int() ^ right.int()}, nil
+	case `\`, "MOD":
+		l, r := left.int(), right.int()
+		if r == 0 {
+			return intp.divisionByZero(be)
+		}
+		if be.Op == "MOD" {
+			return Value{Int: l % r}, nil
+		}
+		return intp.intValue(l/r, be)
+	}
+	return Value{}, fmt.Errorf("unknown operator `%s`", be.Op)
+}
+
+// compares reports whether the result of Compare satisfies the relational
+// operator op.
+func compares(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// intValue returns i as a Value, or an overflow error if it does not fit in
+// the interpreter's IntSize.
+func (bob *Interpreter) intValue(i int, expr Expression) (Value, error) {
+	bits := bob.IntSize
+	if bits == 0 {
+		bits = 32
+	}
+	if max := 1<<(bits-1) - 1; i > max || i < -max-1 {
+		return Value{}, fmt.Errorf("overflow: %s", expr)
+	}
+	return Value{Int: i}, nil
+}
+
+// divisionByZero returns zero if the interpreter has been configured to be
+// lenient about dividing by zero, otherwise an error.
+func (bob *Interpreter) divisionByZero(expr Expression) (Value, error) {
+	if bob.DivZeroZero {
+		return Value{}, nil
+	}
+	return Value{}, fmt.Errorf("%w: %s", ErrDivisionByZero, expr)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isIdentStart(r byte) bool { return unicode.IsLetter(rune(r)) }
+func isIdentChar(r byte) bool  { return unicode.IsLetter(rune(r)) || unicode.IsDigit(rune(r)) }
+func isDigit(r byte) bool      { return r >= '0' && r <= '9' }
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(s); end++ {
+				if s[end] != '"' {
+					continue
+				}
+				if end+1 < len(s) && s[end+1] == '"' {
+					end++
+					continue
+				}
+				break
+			}
+			if end >= len(s) {
+				return nil, parseErrorf(i, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokString, text: getString(s[i : end+1]), pos: i})
+			i = end + 1
+		case isDigit(c) || c == '.':
+			start := i
+			for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+			i += exponentLen(s[i:])
+			tokens = append(tokens, token{kind: tokNumber, text: s[start:i], pos: start})
+		case c == '&':
+			// &H hex or &O octal
+			start := i
+			for i++; i < len(s) && isIdentChar(s[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: s[start:i], pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(s) && isIdentChar(s[i]) {
+				i++
+			}
+			if i < len(s) && strings.IndexByte("$%!#", s[i]) != -1 {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: strings.ToUpper(s[start:i]), pos: start})
+		case c == '<' || c == '>' || c == '=':
+			op := string(c)
+			if i+1 < len(s) && (s[i+1] == '=' || (c == '<' && s[i+1] == '>')) {
+				op += string(s[i+1])
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		case strings.IndexByte(`+-*/\^(),#`, c) != -1:
+			tokens = append(tokens, token{kind: tokOp, text: string(c), pos: i})
+			i++
+		default:
+			return nil, parseErrorf(i, "unexpected character `%c`", c)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+// exponentLen returns the length of the exponent, such as E3 or E-5, at the
+// start of s, or 0 if s does not start with one.
+func exponentLen(s string) int {
+	if len(s) == 0 || (s[0] != 'E' && s[0] != 'e') {
+		return 0
+	}
+	i := 1
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	if i == len(s) || !isDigit(s[i]) {
+		return 0
+	}
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+type exprParser struct {
+	tokens []token
+	idx    int
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.idx] }
+func (p *exprParser) next() token {
+	t := p.tokens[p.idx]
+	if t.kind != tokEOF {
+		p.idx++
+	}
+	return t
+}
+
+func (p *exprParser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseExpression parses an arithmetic expression such as `A*(B+2)`.
+func ParseExpression(s string) (Expression, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := exprParser{tokens: tokens}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, parseErrorf(t.pos, "unexpected `%s` in `%s`", t.text, s)
+	}
+	return expr, nil
+}
+
+// ParseExpressionList parses a comma separated list of expressions.
+func ParseExpressionList(s string) ([]Expression, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := exprParser{tokens: tokens}
+	var exprs []Expression
+	for {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, parseErrorf(t.pos, "unexpected `%s` in `%s`", t.text, s)
+	}
+	return exprs, nil
+}
+
+func (p *exprParser) parseExpr() (Expression, error) {
+	return p.parseLogical(0)
+}
+
+// logicalOps are the bitwise operators from the loosest binding to the
+// tightest, all of which bind more loosely than the relational operators.
+var logicalOps = []string{"XOR", "OR", "AND"}
+
+// parseLogical parses the bitwise operators from logicalOps[level] down.
+func (p *exprParser) parseLogical(level int) (Expression, error) {
+	if level == len(logicalOps) {
+		return p.parseNot()
+	}
+	left, err := p.parseLogical(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(logicalOps[level]) {
+		op := p.next().text
+		right, err := p.parseLogical(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryExpression{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (Expression, error) {
+	if !p.isOp("NOT") {
+		return p.parseRelational()
+	}
+	p.next()
+	expr, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return UnaryExpression{Op: "NOT", Expr: expr}, nil
+}
+
+func (p *exprParser) parseRelational() (Expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("=", "<>", "<", ">", "<=", ">=") {
+		op := p.next().text
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryExpression{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdditive() (Expression, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.next().text
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryExpression{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseTerm() (Expression, error) {
+	left, err := p.parseSign()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/", `\`, "MOD") {
+		op := p.next().text
+		right, err := p.parseSign()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryExpression{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// parseSign parses a leading - or +, which bind tighter than * and / but
+// not as tight as ^, so -2^2 is -4. A sign in front of a number is folded
+// into it.
+func (p *exprParser) parseSign() (Expression, error) {
+	if !p.isOp("-", "+") {
+		return p.parsePower()
+	}
+	op := p.next().text
+	if t := p.peek(); op == "-" && t.kind == tokNumber {
+		// the minus is parsed as part of the literal, so -2147483648 is in
+		// range even though 2147483648 is not
+		if after := p.tokens[p.idx+1]; after.kind != tokOp || after.text != "^" {
+			p.next()
+			return parseNumber("-" + t.text)
+		}
+	}
+	expr, err := p.parseSign()
+	if err != nil {
+		return nil, err
+	}
+	if val, ok := expr.(Value); ok && !val.IsStr {
+		if op == "-" {
+			val = negate(val)
+		}
+		return val, nil
+	}
+	return UnaryExpression{Op: op, Expr: expr}, nil
+}
+
+// parsePower parses ^, which is right associative so 2^3^2 is 2^(3^2).
+func (p *exprParser) parsePower() (Expression, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("^") {
+		return left, nil
+	}
+	p.next()
+	// the exponent may have a sign, 2^-1 is 0.5
+	right, err := p.parseSign()
+	if err != nil {
+		return nil, err
+	}
+	return BinaryExpression{Op: "^", Left: left, Right: right}, nil
+}
+
+func (p *exprParser) parseOperand() (Expression, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return parseNumber(t.text)
+	case tokString:
+		return strValue(t.text), nil
+	case tokIdent:
+		if p.isOp("(") {
+			p.next()
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return FuncExpression{Name: t.text, Args: args}, nil
+		}
+		if _, ok := builtins[t.text]; ok || strings.HasPrefix(t.text, "FN") {
+			// functions without arguments, such as INKEY$
+			return FuncExpression{Name: t.text}, nil
+		}
+		return Reference(t.text), nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOp(")") {
+				return nil, parseErrorf(p.peek().pos, "missing `)`")
+			}
+			p.next()
+			return ParenExpression{Expr: expr}, nil
+		case "#":
+			expr, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return ChannelExpression{Expr: expr}, nil
+		}
+	case tokEOF:
+		return nil, parseErrorf(t.pos, "unexpected end of expression")
+	}
+	return nil, parseErrorf(t.pos, "unexpected `%s`", t.text)
+}
+
+// parseArgs parses a comma separated list of expressions up to and including
+// the closing parenthesis.
+func (p *exprParser) parseArgs() ([]Expression, error) {
+	var args []Expression
+	if p.isOp(")") {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		switch {
+		case p.isOp(","):
+			p.next()
+		case p.isOp(")"):
+			p.next()
+			return args, nil
+		default:
+			return nil, parseErrorf(p.peek().pos, "missing `)`")
+		}
+	}
+}
+
+// evalInt evaluates expr, which must be numeric, as an integer.
+func evalInt(intp *Interpreter, expr Expression) (int, error) {
+	val, err := expr.Eval(intp)
+	if err != nil {
+		return 0, err
+	}
+	if val.IsStr {
+		return 0, fmt.Errorf("type mismatch: %s is not a number", expr)
+	}
+	return val.int(), nil
+}
+
+// evalString evaluates expr, which must be a string.
+func evalString(intp *Interpreter, expr Expression) (string, error) {
+	val, err := expr.Eval(intp)
+	if err != nil {
+		return "", err
+	}
+	if !val.IsStr {
+		return "", fmt.Errorf("type mismatch: %s is not a string", expr)
+	}
+	return val.Str, nil
+}
+
+func (v Value) float() float64 {
+	if v.IsFloat {
+		return v.Float
+	}
+	return float64(v.Int)
+}
+
+func (v Value) int() int {
+	if v.IsFloat {
+		return int(math.Trunc(v.Float))
+	}
+	return v.Int
+}
@@ -0,0 +1,253 @@
+package gdbasic
+
+import (
+	"errors"
+	"testing"
+)
+
+// eval parses and evaluates s with bob.
+func eval(t *testing.T, bob *Interpreter, s string) (Value, error) {
+	t.Helper()
+	expr, err := ParseExpression(s)
+	if err != nil {
+		t.Fatalf("ParseExpression(%q): %v", s, err)
+	}
+	return expr.Eval(bob)
+}
+
+func TestDivZeroZero(t *testing.T) {
+	for _, expr := range []string{`1/0`, `7\0`, `7 MOD 0`, `1.5/0.0`, `A/B`} {
+		t.Run(expr, func(t *testing.T) {
+			bob := NewInterpreter()
+			if _, err := eval(t, bob, expr); !errors.Is(err, ErrDivisionByZero) {
+				t.Errorf("by default got error %v, want ErrDivisionByZero", err)
+			}
+
+			bob.DivZeroZero = true
+			val, err := eval(t, bob, expr)
+			if err != nil {
+				t.Fatalf("with DivZeroZero got error %v", err)
+			}
+			if val.IsStr || val.float() != 0 {
+				t.Errorf("with DivZeroZero got %v, want 0", val)
+			}
+		})
+	}
+}
+
+func TestDivZeroZeroLeavesOtherDivisionAlone(t *testing.T) {
+	bob := NewInterpreter()
+	bob.DivZeroZero = true
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`7/2`, `3.5`},
+		{`7\2`, `3`},
+		{`7 MOD 2`, `1`},
+	}
+	for _, tc := range tests {
+		val, err := eval(t, bob, tc.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.expr, err)
+			continue
+		}
+		if got := val.String(); got != tc.want {
+			t.Errorf("%s = %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestIntSize(t *testing.T) {
+	tests := []struct {
+		expr     string
+		overflow bool // with an IntSize of 16
+		want     string
+	}{
+		{expr: `32766+1`, want: `32767`},
+		{expr: `32767+1`, overflow: true, want: `32768`},
+		{expr: `-32767-1`, want: `-32768`},
+		{expr: `-32768-1`, overflow: true, want: `-32769`},
+		{expr: `182*180`, want: `32760`},
+		{expr: `182*181`, overflow: true, want: `32942`},
+		{expr: `32767+1.0`, want: `32768`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			bob := NewInterpreter()
+			bob.IntSize = 16
+			val, err := eval(t, bob, tc.expr)
+			switch {
+			case tc.overflow && err == nil:
+				t.Errorf("with IntSize 16 got %v, expected an overflow", val)
+			case !tc.overflow && err != nil:
+				t.Errorf("with IntSize 16 got error %v", err)
+			case !tc.overflow && val.String() != tc.want:
+				t.Errorf("with IntSize 16 got %v, want %s", val, tc.want)
+			}
+
+			bob.IntSize = 32
+			val, err = eval(t, bob, tc.expr)
+			if err != nil {
+				t.Fatalf("with IntSize 32 got error %v", err)
+			}
+			if got := val.String(); got != tc.want {
+				t.Errorf("with IntSize 32 got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIntSize32Overflow(t *testing.T) {
+	bob := NewInterpreter()
+	for _, expr := range []string{`2147483647+1`, `-2147483648-1`, `65536*32768`} {
+		if val, err := eval(t, bob, expr); err == nil {
+			t.Errorf("%s = %v, expected an overflow", expr, val)
+		}
+	}
+}
+
+func TestPowerAndMod(t *testing.T) {
+	testExprs(t, []exprTest{
+		{`2^10`, `1024`},
+		{`2^-1`, `0.5`},
+		{`2^3^2`, `512`},
+		{`(2^3)^2`, `64`},
+		{`-2^2`, `-4`},
+		{`(-2)^2`, `4`},
+		{`(-2)^3`, `-8`},
+		{`2*3^2`, `18`},
+		{`10 MOD 3`, `1`},
+		{`-10 MOD 3`, `-1`},
+		{`10 MOD -3`, `1`},
+		{`2+10 MOD 3*2`, `4`},
+		// float operands are truncated
+		{`10.7 MOD 3`, `1`},
+		{`2^3 MOD 3`, `2`},
+	})
+	testExprErrors(t, `10 MOD 0`, `"A"^2`, `2 MOD "A"`)
+	// ^ always gives a float
+	if val, _ := eval(t, NewInterpreter(), `2^2`); !val.IsFloat {
+		t.Errorf("2^2 = %#v, want a float", val)
+	}
+}
+
+func TestBitwise(t *testing.T) {
+	testExprs(t, []exprTest{
+		// masking
+		{`255 AND 15`, `15`},
+		{`&HA5 AND &HF0`, `160`},
+		{`-1 AND 7`, `7`},
+		// setting
+		{`1 OR 128`, `129`},
+		{`8 OR 8`, `8`},
+		// toggling
+		{`5 XOR 1`, `4`},
+		{`4 XOR 1`, `5`},
+		{`255 XOR 255`, `0`},
+		{`NOT 0`, `-1`},
+		{`NOT -1`, `0`},
+		{`NOT 5`, `-6`},
+		// float operands are truncated
+		{`15.9 AND 3.2`, `3`},
+		{`1.5 OR 2.7`, `3`},
+		{`NOT 1.9`, `-2`},
+		// the -1/0 truth values are what make them logical operators
+		{`(1 < 2) AND (2 < 3)`, `-1`},
+		{`(1 > 2) OR (2 < 3)`, `-1`},
+		{`NOT (1 = 1)`, `0`},
+		{`1 AND 2`, `0`},
+		// precedence is NOT, AND, OR then XOR
+		{`1 OR 2 AND 0`, `1`},
+		{`NOT 0 AND 5`, `5`},
+		{`1 XOR 1 OR 1`, `0`},
+	})
+	testExprErrors(t, `"A" AND 1`, `1 OR "B"`, `NOT "C"`)
+}
+
+func TestUnary(t *testing.T) {
+	testExprs(t, []exprTest{
+		{`5--3`, `8`},
+		{`-(-5)`, `5`},
+		{`5-3`, `2`},
+		{`5- -3`, `8`},
+		{`5+-3`, `2`},
+		{`5-+3`, `2`},
+		{`--5`, `5`},
+		{`+5`, `5`},
+		{`-+-5`, `5`},
+		{`-(1+2)`, `-3`},
+		{`2*-3`, `-6`},
+		{`-2*3`, `-6`},
+		{`6/-2`, `-3`},
+		{`-6\4`, `-1`},
+		{`1-2-3`, `-4`},
+		{`1-(2-3)`, `2`},
+		// ^ binds tighter than the sign
+		{`-2^2`, `-4`},
+		{`2^-2`, `0.25`},
+		{`-1.5`, `-1.5`},
+		{`-1.5+1`, `-0.5`},
+		{`3>-1`, `-1`},
+		{`-3<1`, `-1`},
+	})
+	testExprErrors(t, `-"A"`, `-A$`, `+"A"`, `5-"A"`)
+	for _, expr := range []string{`5-`, `-`, `5*-`} {
+		if _, err := ParseExpression(expr); err == nil {
+			t.Errorf("%s: expected a parse error", expr)
+		}
+	}
+
+	bob := NewInterpreter()
+	bob.Variables["B"] = Value{Int: 4}
+	bob.Variables["X"] = Value{Float: 1.5, IsFloat: true}
+	for expr, want := range map[string]string{
+		`-B`:     `-4`,
+		`-(X+1)`: `-2.5`,
+		`B--B`:   `8`,
+		`-B^2`:   `-16`,
+	} {
+		got, err := eval(t, bob, expr)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+		} else if got.String() != want {
+			t.Errorf("%s = %s, want %s", expr, got, want)
+		}
+	}
+}
+
+func TestScientificNotation(t *testing.T) {
+	testExprs(t, []exprTest{
+		{`1.5E3`, `1500`},
+		{`1E3`, `1000`},
+		{`1e3`, `1000`},
+		{`2E+2`, `200`},
+		{`1E-5`, `1e-05`},
+		{`2.5E-1`, `0.25`},
+		{`6.02E23`, `6.02e+23`},
+		{`-1.5E3`, `-1500`},
+		// the sign of the exponent is part of the number, anything after
+		// it is not
+		{`2E3+1`, `2001`},
+		{`2E3-1`, `1999`},
+		{`2E-3*1000`, `2`},
+	})
+	for _, expr := range []string{`1E3`, `1E0`, `5E-1`} {
+		val, err := eval(t, NewInterpreter(), expr)
+		if err != nil || !val.IsFloat {
+			t.Errorf("%s = %#v, %v, want a float", expr, val, err)
+		}
+	}
+	if _, err := ParseExpression(`1E2E`); err == nil {
+		t.Error("1E2E: expected a parse error")
+	}
+	// E on its own, or after an operator, is a variable
+	bob := NewInterpreter()
+	bob.Variables["E"] = Value{Int: 3}
+	for expr, want := range map[string]string{`2*E`: `6`, `E+1`: `4`, `E-1E1`: `-7`} {
+		val, err := eval(t, bob, expr)
+		if err != nil || val.String() != want {
+			t.Errorf("%s = %s, %v, want %s", expr, val, err, want)
+		}
+	}
+}
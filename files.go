@@ -0,0 +1,488 @@
+package gdbasic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// File is a file opened by OPEN.
+type File interface {
+	io.ReadWriteSeeker
+	io.Closer
+}
+
+// FileSystem opens the files used by OPEN, flag is as for os.OpenFile.
+type FileSystem interface {
+	OpenFile(name string, flag int) (File, error)
+}
+
+// OSFileSystem is a FileSystem backed by the operating system.
+type OSFileSystem struct{}
+
+func (OSFileSystem) OpenFile(name string, flag int) (File, error) {
+	f, err := os.OpenFile(name, flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// defaultRecordLen is the record length of a random access file opened
+// without one.
+const defaultRecordLen = 128
+
+// channel is an open file, known to the program by its number.
+type channel struct {
+	file   File
+	random bool
+	recLen int
+	fields []field
+	// next is the record used by GET and PUT without a record number.
+	next int
+}
+
+// field maps part of a random access record to a string variable.
+type field struct {
+	width   int
+	varName string
+}
+
+// channel returns the open channel with the number.
+func (bob *Interpreter) channel(num int) (*channel, error) {
+	ch, ok := bob.channels[num]
+	if !ok {
+		return nil, fmt.Errorf("bad file number #%d", num)
+	}
+	return ch, nil
+}
+
+// parseChannel parses a channel number such as `#1`.
+func parseChannel(s string) (Expression, error) {
+	s = strings.TrimSpace(s)
+	return ParseExpression(strings.TrimPrefix(s, "#"))
+}
+
+// OpenInstruction opens a file on a channel, using the classic form
+// `OPEN mode, #n, name[, record length]` where mode is "I" for input, "O"
+// for output, "A" for append or "R" for random access.
+type OpenInstruction struct {
+	Mode      Expression
+	Channel   Expression
+	Name      Expression
+	RecordLen Expression
+}
+
+func (oi OpenInstruction) Execute(intp *Interpreter) error {
+	mode, err := evalString(intp, oi.Mode)
+	if err != nil {
+		return err
+	}
+	num, err := evalInt(intp, oi.Channel)
+	if err != nil {
+		return err
+	}
+	name, err := evalString(intp, oi.Name)
+	if err != nil {
+		return err
+	}
+	recLen := defaultRecordLen
+	if oi.RecordLen != nil {
+		if recLen, err = evalInt(intp, oi.RecordLen); err != nil {
+			return err
+		}
+		if recLen <= 0 {
+			return fmt.Errorf("open: bad record length %d", recLen)
+		}
+	}
+	var flag int
+	switch strings.ToUpper(mode) {
+	case "I":
+		flag = os.O_RDONLY
+	case "O":
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	case "A":
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	case "R":
+		flag = os.O_RDWR | os.O_CREATE
+	default:
+		return fmt.Errorf("open: bad file mode `%s`", mode)
+	}
+	if _, ok := intp.channels[num]; ok {
+		return fmt.Errorf("file #%d already open", num)
+	}
+	file, err := intp.Files.OpenFile(name, flag)
+	if err != nil {
+		return err
+	}
+	if intp.channels == nil {
+		intp.channels = map[int]*channel{}
+	}
+	intp.channels[num] = &channel{file: file, random: flag&os.O_RDWR != 0, recLen: recLen, next: 1}
+	return nil
+}
+
+func (oi OpenInstruction) String() string {
+	if oi.RecordLen == nil {
+		return fmt.Sprintf("OPEN %s,#%s,%s", oi.Mode, oi.Channel, oi.Name)
+	}
+	return fmt.Sprintf("OPEN %s,#%s,%s,%s", oi.Mode, oi.Channel, oi.Name, oi.RecordLen)
+}
+
+func NewOpenInstruction(_ int, remainder string) (*OpenInstruction, error) {
+	// OPEN "R", #1, "DATA.DAT", 14
+	parts := splitOutsideQuotes(remainder, ",")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, fmt.Errorf("open: expected mode, file number, name and optional record length")
+	}
+	var (
+		oi  OpenInstruction
+		err error
+	)
+	if oi.Mode, err = ParseExpression(parts[0]); err != nil {
+		return nil, fmt.Errorf("open: %v", err)
+	}
+	if oi.Channel, err = parseChannel(parts[1]); err != nil {
+		return nil, fmt.Errorf("open: %v", err)
+	}
+	if oi.Name, err = ParseExpression(parts[2]); err != nil {
+		return nil, fmt.Errorf("open: %v", err)
+	}
+	if len(parts) == 4 {
+		if oi.RecordLen, err = ParseExpression(parts[3]); err != nil {
+			return nil, fmt.Errorf("open: %v", err)
+		}
+	}
+	return &oi, nil
+}
+
+// CloseInstruction closes the listed channels, or all of them if none are
+// listed.
+type CloseInstruction struct {
+	Channels []Expression
+}
+
+func (ci CloseInstruction) Execute(intp *Interpreter) error {
+	if len(ci.Channels) == 0 {
+		for num, ch := range intp.channels {
+			delete(intp.channels, num)
+			if err := ch.file.Close(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, expr := range ci.Channels {
+		num, err := evalInt(intp, expr)
+		if err != nil {
+			return err
+		}
+		ch, err := intp.channel(num)
+		if err != nil {
+			return err
+		}
+		delete(intp.channels, num)
+		if err = ch.file.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ci CloseInstruction) String() string {
+	if len(ci.Channels) == 0 {
+		return "CLOSE"
+	}
+	nums := make([]string, len(ci.Channels))
+	for i := range ci.Channels {
+		nums[i] = "#" + ci.Channels[i].String()
+	}
+	return "CLOSE " + strings.Join(nums, ",")
+}
+
+func NewCloseInstruction(_ int, remainder string) (*CloseInstruction, error) {
+	// CLOSE #1, #2
+	ci := new(CloseInstruction)
+	if strings.TrimSpace(remainder) == "" {
+		return ci, nil
+	}
+	for _, part := range splitOutsideQuotes(remainder, ",") {
+		num, err := parseChannel(part)
+		if err != nil {
+			return nil, fmt.Errorf("close: %v", err)
+		}
+		ci.Channels = append(ci.Channels, num)
+	}
+	return ci, nil
+}
+
+// FieldInstruction maps the record of a random access channel onto string
+// variables, which GET fills in from the record and PUT writes back to it.
+type FieldInstruction struct {
+	Channel Expression
+	Widths  []int
+	Vars    []string
+}
+
+func (fi FieldInstruction) Execute(intp *Interpreter) error {
+	num, err := evalInt(intp, fi.Channel)
+	if err != nil {
+		return err
+	}
+	ch, err := intp.channel(num)
+	if err != nil {
+		return err
+	}
+	fields := make([]field, len(fi.Vars))
+	total := 0
+	for i := range fi.Vars {
+		fields[i] = field{width: fi.Widths[i], varName: fi.Vars[i]}
+		total += fi.Widths[i]
+	}
+	if total > ch.recLen {
+		return fmt.Errorf("field overflow: %d bytes in a record of %d", total, ch.recLen)
+	}
+	ch.fields = fields
+	return nil
+}
+
+func (fi FieldInstruction) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "FIELD #%s", fi.Channel)
+	for i := range fi.Vars {
+		fmt.Fprintf(&buf, ",%d AS %s", fi.Widths[i], fi.Vars[i])
+	}
+	return buf.String()
+}
+
+func NewFieldInstruction(_ int, remainder string) (*FieldInstruction, error) {
+	// FIELD #1, 10 AS N$, 4 AS A$
+	parts := splitOutsideQuotes(remainder, ",")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("field: expected a file number and fields")
+	}
+	num, err := parseChannel(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("field: %v", err)
+	}
+	fi := &FieldInstruction{Channel: num}
+	for _, part := range parts[1:] {
+		idx := strings.Index(part, " AS ")
+		if idx == -1 {
+			return nil, fmt.Errorf("field: expected `width AS name` got `%s`", strings.TrimSpace(part))
+		}
+		width, err := parseNumber(part[:idx])
+		if err != nil || width.IsFloat || width.Int <= 0 {
+			return nil, fmt.Errorf("field: bad width `%s`", strings.TrimSpace(part[:idx]))
+		}
+		name := varName(part[idx+len(" AS "):])
+		if !isStringVar(name) {
+			return nil, fmt.Errorf("field: `%s` is not a string variable", name)
+		}
+		fi.Widths = append(fi.Widths, width.Int)
+		fi.Vars = append(fi.Vars, name)
+	}
+	return fi, nil
+}
+
+// RecordInstruction is GET or PUT, reading or writing a record of a random
+// access channel. Without a record number the record after the last one used
+// is read or written.
+type RecordInstruction struct {
+	Put     bool
+	Channel Expression
+	Record  Expression
+}
+
+func (ri RecordInstruction) Execute(intp *Interpreter) error {
+	num, err := evalInt(intp, ri.Channel)
+	if err != nil {
+		return err
+	}
+	ch, err := intp.channel(num)
+	if err != nil {
+		return err
+	}
+	if !ch.random {
+		return fmt.Errorf("file #%d is not open for random access", num)
+	}
+	rec := ch.next
+	if ri.Record != nil {
+		if rec, err = evalInt(intp, ri.Record); err != nil {
+			return err
+		}
+	}
+	if rec < 1 {
+		return fmt.Errorf("bad record number %d", rec)
+	}
+	if _, err = ch.file.Seek(int64(rec-1)*int64(ch.recLen), io.SeekStart); err != nil {
+		return err
+	}
+	ch.next = rec + 1
+
+	buf := make([]byte, ch.recLen)
+	if ri.Put {
+		offset := 0
+		for _, f := range ch.fields {
+			val, err := Reference(f.varName).Eval(intp)
+			if err != nil {
+				return err
+			}
+			offset += copy(buf[offset:offset+f.width], stringField(f.width).format(val.Str))
+		}
+		for ; offset < len(buf); offset++ {
+			buf[offset] = ' '
+		}
+		_, err = ch.file.Write(buf)
+		return err
+	}
+
+	n, err := io.ReadFull(ch.file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	// a record cut short by the end of the file is padded with spaces, as
+	// PUT pads its fields, so reading past the end gives a blank record
+	for ; n < len(buf); n++ {
+		buf[n] = ' '
+	}
+	offset := 0
+	for _, f := range ch.fields {
+		if err := intp.setVariable(f.varName, strValue(string(buf[offset:offset+f.width]))); err != nil {
+			return err
+		}
+		offset += f.width
+	}
+	return nil
+}
+
+func (ri RecordInstruction) String() string {
+	cmd := "GET"
+	if ri.Put {
+		cmd = "PUT"
+	}
+	if ri.Record == nil {
+		return fmt.Sprintf("%s #%s", cmd, ri.Channel)
+	}
+	return fmt.Sprintf("%s #%s,%s", cmd, ri.Channel, ri.Record)
+}
+
+func NewRecordInstruction(put bool, remainder string) (*RecordInstruction, error) {
+	// GET #1, 3 or PUT #1
+	parts := splitOutsideQuotes(remainder, ",")
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("expected a file number and optional record number")
+	}
+	num, err := parseChannel(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	ri := &RecordInstruction{Put: put, Channel: num}
+	if len(parts) == 2 {
+		if ri.Record, err = ParseExpression(parts[1]); err != nil {
+			return nil, err
+		}
+	}
+	return ri, nil
+}
+
+// position returns the position of the channel: the next record for random
+// access files, otherwise the 1 based byte offset.
+func (ch *channel) position() (int, error) {
+	if ch.random {
+		return ch.next, nil
+	}
+	offset, err := ch.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	return int(offset) + 1, nil
+}
+
+// seek moves the channel to pos, see position.
+func (ch *channel) seek(pos int) error {
+	if pos < 1 {
+		return fmt.Errorf("bad file position %d", pos)
+	}
+	if ch.random {
+		ch.next = pos
+		return nil
+	}
+	cur, err := ch.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	size, err := ch.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if int64(pos-1) > size {
+		// leave the file where it was
+		if _, err := ch.file.Seek(cur, io.SeekStart); err != nil {
+			return err
+		}
+		return fmt.Errorf("file position %d past end of file", pos)
+	}
+	_, err = ch.file.Seek(int64(pos-1), io.SeekStart)
+	return err
+}
+
+// SeekInstruction moves a channel to a position, the record number for random
+// access files or the byte position, starting at 1, for other files.
+type SeekInstruction struct {
+	Channel  Expression
+	Position Expression
+}
+
+func (si SeekInstruction) Execute(intp *Interpreter) error {
+	num, err := evalInt(intp, si.Channel)
+	if err != nil {
+		return err
+	}
+	pos, err := evalInt(intp, si.Position)
+	if err != nil {
+		return err
+	}
+	ch, err := intp.channel(num)
+	if err != nil {
+		return err
+	}
+	return ch.seek(pos)
+}
+
+func (si SeekInstruction) String() string {
+	return fmt.Sprintf("SEEK #%s,%s", si.Channel, si.Position)
+}
+
+func NewSeekInstruction(_ int, remainder string) (*SeekInstruction, error) {
+	// SEEK #1, 10
+	parts := splitOutsideQuotes(remainder, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("seek: expected a file number and position")
+	}
+	num, err := parseChannel(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("seek: %v", err)
+	}
+	pos, err := ParseExpression(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("seek: %v", err)
+	}
+	return &SeekInstruction{Channel: num, Position: pos}, nil
+}
+
+func fnSeek(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("SEEK", args, "#"); err != nil {
+		return Value{}, err
+	}
+	ch, err := intp.channel(args[0].int())
+	if err != nil {
+		return Value{}, err
+	}
+	pos, err := ch.position()
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Int: pos}, nil
+}
@@ -0,0 +1,294 @@
+package gdbasic
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// memFS is a FileSystem of files held in memory.
+type memFS map[string]*[]byte
+
+func (fs memFS) OpenFile(name string, flag int) (File, error) {
+	data, ok := fs[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, os.ErrNotExist
+		}
+		data = new([]byte)
+		fs[name] = data
+	}
+	if flag&os.O_TRUNC != 0 {
+		*data = (*data)[:0]
+	}
+	f := &memFile{data: data}
+	if flag&os.O_APPEND != 0 {
+		f.pos = int64(len(*data))
+	}
+	return f, nil
+}
+
+// contents returns the contents of the named file.
+func (fs memFS) contents(name string) string {
+	if data, ok := fs[name]; ok {
+		return string(*data)
+	}
+	return ""
+}
+
+// memFile is a file opened from a memFS.
+type memFile struct {
+	data *[]byte
+	pos  int64
+}
+
+func (mf *memFile) Read(p []byte) (int, error) {
+	if mf.pos >= int64(len(*mf.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, (*mf.data)[mf.pos:])
+	mf.pos += int64(n)
+	return n, nil
+}
+
+func (mf *memFile) Write(p []byte) (int, error) {
+	if end := mf.pos + int64(len(p)); end > int64(len(*mf.data)) {
+		*mf.data = append(*mf.data, make([]byte, end-int64(len(*mf.data)))...)
+	}
+	copy((*mf.data)[mf.pos:], p)
+	mf.pos += int64(len(p))
+	return len(p), nil
+}
+
+func (mf *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += mf.pos
+	case io.SeekEnd:
+		offset += int64(len(*mf.data))
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	mf.pos = offset
+	return offset, nil
+}
+
+func (mf *memFile) Close() error { return nil }
+
+// runWithFiles runs the program with files in fs and returns what it printed.
+func runWithFiles(t *testing.T, fs memFS, lines ...string) string {
+	t.Helper()
+	bob, out := newTestInterpreter(t, lines...)
+	bob.Files = fs
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return out.String()
+}
+
+func TestFieldGetPut(t *testing.T) {
+	fs := memFS{}
+	got := runWithFiles(t, fs,
+		`10 OPEN "R", #1, "PEOPLE.DAT", 8`,
+		`20 FIELD #1, 5 AS N$, 3 AS A$`,
+		`30 LET N$="ALEXANDER"`,
+		`40 LET A$="42"`,
+		`50 PUT #1`,
+		`60 LET N$="BOB"`,
+		`70 LET A$="7"`,
+		`80 PUT #1`,
+		`90 GET #1, 1`,
+		`100 PRINT "["; N$; "]["; A$; "]"`,
+		`110 GET #1`,
+		`120 PRINT "["; N$; "]["; A$; "]"`,
+		`130 CLOSE #1`,
+	)
+	// fields are truncated or padded with spaces to their width
+	if want := "ALEXA42 BOB  7  "; fs.contents("PEOPLE.DAT") != want {
+		t.Errorf("file holds %q, want %q", fs.contents("PEOPLE.DAT"), want)
+	}
+	if want := "[ALEXA][42 ]\n[BOB  ][7  ]\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestGetShortRecord(t *testing.T) {
+	data := []byte("ABCDEFGHIJKLM")
+	fs := memFS{"SHORT.DAT": &data}
+	got := runWithFiles(t, fs,
+		`10 OPEN "R", #1, "SHORT.DAT", 10`,
+		`20 FIELD #1, 6 AS A$, 4 AS B$`,
+		`30 GET #1, 2`,
+		`40 PRINT "["; A$; "]["; B$; "]"`,
+		`50 GET #1, 5`,
+		`60 PRINT "["; A$; "]["; B$; "]"`,
+	)
+	// the last record is only 3 bytes long, and record 5 is past the end
+	if want := "[KLM   ][    ]\n[      ][    ]\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+	}{
+		{"fields wider than the record", []string{`10 OPEN "R", #1, "F", 4`, `20 FIELD #1, 3 AS A$, 2 AS B$`}},
+		{"channel not open", []string{`10 FIELD #2, 3 AS A$`}},
+		{"not random access", []string{`10 OPEN "O", #1, "F"`, `20 GET #1`}},
+		{"bad record number", []string{`10 OPEN "R", #1, "F", 4`, `20 GET #1, 0`}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tc.program...)
+			bob.Files = memFS{}
+			if err := bob.Run(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestFieldParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		err  bool
+	}{
+		{line: `FIELD #1, 10 AS N$, 4 AS A$`, want: `FIELD #1,10 AS N$,4 AS A$`},
+		{line: `GET #1, 3`, want: `GET #1,3`},
+		{line: `PUT #1`, want: `PUT #1`},
+		{line: `FIELD #1, 10 AS N`, err: true},
+		{line: `FIELD #1, 0 AS N$`, err: true},
+		{line: `FIELD #1, 10 N$`, err: true},
+		{line: `FIELD #1`, err: true},
+		{line: `GET #1, 2, 3`, err: true},
+	}
+	for _, tc := range tests {
+		ins, err := parseInstruction(10, tc.line)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.line, err)
+			continue
+		}
+		if got := ins.String(); got != tc.want {
+			t.Errorf("%s: listed as %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestOpenClose(t *testing.T) {
+	fs := memFS{}
+	runWithFiles(t, fs,
+		`10 OPEN "O", #1, "A.TXT"`,
+		`20 OPEN "O", #2, "B.TXT"`,
+		`30 CLOSE`,
+		`40 OPEN "O", #1, "A.TXT"`,
+		`50 CLOSE #1`,
+	)
+	for _, name := range []string{"A.TXT", "B.TXT"} {
+		if _, ok := fs[name]; !ok {
+			t.Errorf("%s was not created", name)
+		}
+	}
+
+	for _, program := range [][]string{
+		{`10 OPEN "I", #1, "MISSING.TXT"`},
+		{`10 OPEN "X", #1, "A.TXT"`},
+		{`10 OPEN "O", #1, "A.TXT"`, `20 OPEN "O", #1, "B.TXT"`},
+		{`10 CLOSE #1`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		bob.Files = fs
+		if err := bob.Run(); err == nil {
+			t.Errorf("%s: expected an error", strings.Join(program, " / "))
+		}
+	}
+}
+
+func TestSeekSequential(t *testing.T) {
+	data := []byte("HELLO WORLD")
+	fs := memFS{"HELLO.TXT": &data}
+	got := runWithFiles(t, fs,
+		`10 OPEN "I", #1, "HELLO.TXT"`,
+		`20 PRINT SEEK(1)`,
+		`30 SEEK #1, 7`,
+		`40 PRINT INPUT$(5, #1)`,
+		`50 PRINT SEEK(1)`,
+		`60 SEEK #1, 1`,
+		`70 PRINT INPUT$(5, #1)`,
+	)
+	if want := "1\nWORLD\n12\nHELLO\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestSeekRandom(t *testing.T) {
+	fs := memFS{}
+	got := runWithFiles(t, fs,
+		`10 OPEN "R", #1, "R.DAT", 2`,
+		`20 FIELD #1, 2 AS A$`,
+		`30 SEEK #1, 3`,
+		`40 LET A$="CC"`,
+		`50 PUT #1`,
+		`60 PRINT SEEK(1)`,
+		`70 SEEK #1, 1`,
+		`80 LET A$="AA"`,
+		`90 PUT #1`,
+		`100 PRINT SEEK(1)`,
+	)
+	// record 2 was never written
+	if want := "AA\x00\x00CC"; fs.contents("R.DAT") != want {
+		t.Errorf("file holds %q, want %q", fs.contents("R.DAT"), want)
+	}
+	if want := "4\n2\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestSeekErrors(t *testing.T) {
+	data := []byte("ABC")
+	for _, program := range [][]string{
+		{`10 OPEN "I", #1, "ABC.TXT"`, `20 SEEK #1, 5`},
+		{`10 OPEN "I", #1, "ABC.TXT"`, `20 SEEK #1, 0`},
+		{`10 SEEK #2, 1`},
+		{`10 PRINT SEEK(2)`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		bob.Files = memFS{"ABC.TXT": &data}
+		if err := bob.Run(); err == nil {
+			t.Errorf("%q: expected an error", program)
+		}
+	}
+}
+
+func TestSeekPastEndKeepsPosition(t *testing.T) {
+	data := []byte("HELLO WORLD")
+	bob, out := newTestInterpreter(t,
+		`10 OPEN "I", #1, "HELLO.TXT"`,
+		`20 ON ERROR GOTO 100`,
+		`30 PRINT INPUT$(6, #1)`,
+		`40 SEEK #1, 99`,
+		`50 PRINT SEEK(1); INPUT$(5, #1)`,
+		`60 GOTO 200`,
+		`100 PRINT "ERR"`,
+		`110 RESUME NEXT`,
+		`200 REM`,
+	)
+	bob.Files = memFS{"HELLO.TXT": &data}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// the failed SEEK leaves the next read where it would have been
+	if want := "HELLO \nERR\n7WORLD\n"; out.String() != want {
+		t.Errorf("printed %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,254 @@
+package gdbasic
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// numberField is a numeric field of a PRINT USING mask, such as `+$#,###.##`.
+type numberField struct {
+	// digits is the number of positions before the decimal point, commas
+	// included.
+	digits int
+	// decimals is the number of digits after the decimal point, or -1 if
+	// there is no decimal point.
+	decimals int
+	// comma groups the digits into thousands.
+	comma bool
+	// dollar puts a $ at the start of the field, or with floating
+	// immediately to the left of the number.
+	dollar   bool
+	floating bool
+	// lead and trail are the '+' or '-' sign characters before or after the
+	// field, or 0.
+	lead  byte
+	trail byte
+}
+
+// width is the number of characters the field takes up.
+func (nf numberField) width() int {
+	w := nf.digits
+	if nf.decimals >= 0 {
+		w += 1 + nf.decimals
+	}
+	if nf.dollar {
+		w++
+	}
+	if nf.lead != 0 {
+		w++
+	}
+	if nf.trail != 0 {
+		w++
+	}
+	return w
+}
+
+func (nf numberField) format(f float64) string {
+	neg := f < 0
+	if neg {
+		f = -f
+	}
+	decimals := nf.decimals
+	if decimals < 0 {
+		decimals = 0
+	}
+	// round half away from zero, as BASIC does, rather than to even
+	scale := math.Pow10(decimals)
+	num := strconv.FormatFloat(math.Round(f*scale)/scale, 'f', decimals, 64)
+	intPart, frac := num, ""
+	if idx := strings.IndexByte(num, '.'); idx != -1 {
+		intPart, frac = num[:idx], num[idx+1:]
+	}
+	if intPart == "0" && nf.digits == 0 {
+		intPart = ""
+	}
+	if nf.comma {
+		intPart = groupThousands(intPart)
+	}
+
+	sign := "+"
+	if neg {
+		sign = "-"
+	}
+	body := intPart
+	avail := nf.digits
+	if nf.floating {
+		body = "$" + body
+		avail++
+	}
+	switch {
+	case nf.lead != 0:
+		body = sign + body
+		avail++
+	case neg && nf.trail == 0:
+		body = "-" + body
+	}
+	if nf.decimals >= 0 {
+		body += "." + frac
+		avail += 1 + nf.decimals
+	}
+	if len(body) > avail {
+		return strings.Repeat("*", nf.width())
+	}
+
+	var buf strings.Builder
+	if nf.dollar && !nf.floating {
+		buf.WriteByte('$')
+	}
+	buf.WriteString(strings.Repeat(" ", avail-len(body)))
+	buf.WriteString(body)
+	switch {
+	case nf.trail == '+':
+		buf.WriteString(sign)
+	case nf.trail == '-' && neg:
+		buf.WriteByte('-')
+	case nf.trail == '-':
+		buf.WriteByte(' ')
+	}
+	return buf.String()
+}
+
+// groupThousands puts a comma between every group of three digits.
+func groupThousands(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var buf strings.Builder
+	first := len(digits) % 3
+	if first == 0 {
+		first = 3
+	}
+	buf.WriteString(digits[:first])
+	for i := first; i < len(digits); i += 3 {
+		buf.WriteByte(',')
+		buf.WriteString(digits[i : i+3])
+	}
+	return buf.String()
+}
+
+// scanNumberField looks for a numeric field starting at mask[i], returning
+// the field and the index just past it.
+func scanNumberField(mask string, i int) (nf numberField, end int, ok bool) {
+	nf.decimals = -1
+	start := i
+	if i < len(mask) && mask[i] == '+' {
+		nf.lead = '+'
+		i++
+	}
+	switch {
+	case strings.HasPrefix(mask[i:], "$$"):
+		// one position for the $ and one for a digit
+		nf.dollar, nf.floating = true, true
+		nf.digits++
+		i += 2
+	case i < len(mask) && mask[i] == '$':
+		nf.dollar = true
+		i++
+	}
+	for ; i < len(mask) && (mask[i] == '#' || (mask[i] == ',' && i > start && nf.digits > 0)); i++ {
+		if mask[i] == ',' {
+			nf.comma = true
+		}
+		nf.digits++
+	}
+	if i+1 < len(mask) && mask[i] == '.' && mask[i+1] == '#' {
+		nf.decimals = 0
+		for i++; i < len(mask) && mask[i] == '#'; i++ {
+			nf.decimals++
+		}
+	} else if i < len(mask) && mask[i] == '.' && nf.digits > 0 {
+		nf.decimals = 0
+		i++
+	}
+	if nf.digits == 0 && nf.decimals <= 0 && !nf.floating {
+		return numberField{}, start, false
+	}
+	if nf.lead == 0 && i < len(mask) && (mask[i] == '+' || mask[i] == '-') {
+		nf.trail = mask[i]
+		i++
+	}
+	return nf, i, true
+}
+
+// stringField is a string field of a PRINT USING mask: `!` is the first
+// character, `\  \` is as many characters as the field is wide and `&` is the
+// whole string, which is represented by a width of -1.
+type stringField int
+
+func (sf stringField) format(s string) string {
+	width := int(sf)
+	switch {
+	case width < 0:
+		return s
+	case len(s) >= width:
+		return s[:width]
+	default:
+		return s + strings.Repeat(" ", width-len(s))
+	}
+}
+
+// scanStringField looks for a string field starting at mask[i], returning
+// the field and the index just past it.
+func scanStringField(mask string, i int) (sf stringField, end int, ok bool) {
+	switch mask[i] {
+	case '&':
+		return -1, i + 1, true
+	case '!':
+		return 1, i + 1, true
+	case '\\':
+		j := i + 1
+		for j < len(mask) && mask[j] == ' ' {
+			j++
+		}
+		if j < len(mask) && mask[j] == '\\' {
+			return stringField(j - i + 1), j + 1, true
+		}
+	}
+	return 0, i, false
+}
+
+// formatUsing formats values according to a PRINT USING style mask. Characters
+// in the mask that are not part of a field are copied as is. If there are more
+// values than fields the mask is reused from the start.
+func formatUsing(mask string, values []Value) (string, error) {
+	var buf strings.Builder
+	for len(values) > 0 {
+		used := false
+		for i := 0; i < len(mask); {
+			nf, end, isNumber := scanNumberField(mask, i)
+			var (
+				sf       stringField
+				isString bool
+			)
+			if !isNumber {
+				sf, end, isString = scanStringField(mask, i)
+			}
+			if !isNumber && !isString {
+				buf.WriteByte(mask[i])
+				i++
+				continue
+			}
+			if len(values) == 0 {
+				// stop at the first field without a value
+				return buf.String(), nil
+			}
+			if values[0].IsStr != isString {
+				return "", fmt.Errorf("type mismatch: formatting %s with `%s`", values[0], mask[i:end])
+			}
+			if isString {
+				buf.WriteString(sf.format(values[0].Str))
+			} else {
+				buf.WriteString(nf.format(values[0].float()))
+			}
+			values = values[1:]
+			used = true
+			i = end
+		}
+		if !used {
+			return "", fmt.Errorf("no fields in format `%s`", mask)
+		}
+	}
+	return buf.String(), nil
+}
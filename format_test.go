@@ -0,0 +1,112 @@
+package gdbasic
+
+import "testing"
+
+type formatTest struct {
+	mask   string
+	values []Value
+	want   string
+}
+
+func testFormatUsing(t *testing.T, tests []formatTest) {
+	t.Helper()
+	for _, tc := range tests {
+		got, err := formatUsing(tc.mask, tc.values)
+		if err != nil {
+			t.Errorf("formatUsing(%q, %v): unexpected error: %v", tc.mask, tc.values, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("formatUsing(%q, %v) = %q, want %q", tc.mask, tc.values, got, tc.want)
+		}
+	}
+}
+
+func TestFormatUsingNumbers(t *testing.T) {
+	testFormatUsing(t, []formatTest{
+		{"###.##", []Value{floatValue(3.14159)}, "  3.14"},
+		{"###.##", []Value{floatValue(-3.14159)}, " -3.14"},
+		{"###.##", []Value{{Int: 42}}, " 42.00"},
+		{"###", []Value{floatValue(2.5)}, "  3"},
+		{"###", []Value{floatValue(-2.5)}, " -3"},
+		{"##.##", []Value{floatValue(123.4)}, "*****"},
+		{".##", []Value{floatValue(0.5)}, ".50"},
+		{"+##.#", []Value{floatValue(1.25)}, " +1.3"},
+		{"+##.#", []Value{floatValue(-1.25)}, " -1.3"},
+		{"##.#-", []Value{floatValue(-1.5)}, " 1.5-"},
+		{"##.#-", []Value{floatValue(1.5)}, " 1.5 "},
+		{"##.#+", []Value{floatValue(1.5)}, " 1.5+"},
+		{"TOTAL: ##", []Value{{Int: 7}}, "TOTAL:  7"},
+		// the mask is reused when there are more values than fields
+		{"## ", []Value{{Int: 1}, {Int: 2}}, " 1  2 "},
+	})
+}
+
+func TestFormatUsingErrors(t *testing.T) {
+	tests := []struct {
+		mask   string
+		values []Value
+	}{
+		{"NO FIELDS", []Value{{Int: 1}}},
+		{"###", []Value{strValue("A")}},
+	}
+	for _, tc := range tests {
+		if got, err := formatUsing(tc.mask, tc.values); err == nil {
+			t.Errorf("formatUsing(%q, %v) = %q, expected an error", tc.mask, tc.values, got)
+		}
+	}
+}
+
+func TestFormatFunction(t *testing.T) {
+	got := runProgram(t,
+		`10 LET A=3.14159`,
+		`20 PRINT "["; FORMAT$(A, "##.##"); "]"`,
+		`30 PRINT USING "[##.##]"; A`,
+	)
+	// FORMAT$ and PRINT USING share the same engine
+	if want := "[ 3.14]\n[ 3.14]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatUsingCurrency(t *testing.T) {
+	testFormatUsing(t, []formatTest{
+		{"$###.##", []Value{floatValue(12.5)}, "$ 12.50"},
+		{"$$###.##", []Value{floatValue(12.5)}, "  $12.50"},
+		{"$$###.##", []Value{floatValue(-12.5)}, " -$12.50"},
+		{"#,###.##", []Value{floatValue(1234.5)}, "1,234.50"},
+		{"#,###", []Value{{Int: 12}}, "   12"},
+		{"##,###", []Value{{Int: 1234567}}, "******"},
+		{"$$#,###.##", []Value{floatValue(1234.5)}, " $1,234.50"},
+		{"+$$#,###", []Value{{Int: 1234}}, " +$1,234"},
+	})
+}
+
+func TestFormatUsingStrings(t *testing.T) {
+	testFormatUsing(t, []formatTest{
+		{"!", []Value{strValue("ABC")}, "A"},
+		{"!", []Value{strValue("")}, " "},
+		{`\  \`, []Value{strValue("ABCDEF")}, "ABCD"},
+		{`\  \`, []Value{strValue("AB")}, "AB  "},
+		{`\\`, []Value{strValue("ABC")}, "AB"},
+		{"&", []Value{strValue("WHOLE STRING")}, "WHOLE STRING"},
+		{"[&]", []Value{strValue("")}, "[]"},
+		{"NAME: & AGE ##", []Value{strValue("BOB"), {Int: 42}}, "NAME: BOB AGE 42"},
+		{"!.", []Value{strValue("JOHN"), strValue("QUINCY")}, "J.Q."},
+	})
+}
+
+func TestFormatUsingStringTypeMismatch(t *testing.T) {
+	for _, tc := range []struct {
+		mask string
+		val  Value
+	}{
+		{"!", Value{Int: 1}},
+		{"&", floatValue(1.5)},
+		{`\  \`, Value{Int: 1}},
+	} {
+		if _, err := formatUsing(tc.mask, []Value{tc.val}); err == nil {
+			t.Errorf("formatUsing(%q, %v): expected a type mismatch", tc.mask, tc.val)
+		}
+	}
+}
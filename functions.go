@@ -0,0 +1,377 @@
+package gdbasic
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// builtinFunc is a function that can be called from an expression.
+type builtinFunc func(intp *Interpreter, args []Value) (Value, error)
+
+var builtins = map[string]builtinFunc{
+	"ASC":     fnAsc,
+	"ATN":     fnAtn,
+	"CDBL":    fnCdbl,
+	"CHR$":    fnChr,
+	"CINT":    fnCint,
+	"CLNG":    fnClng,
+	"COS":     trigFunc("COS", math.Cos),
+	"CSNG":    fnCsng,
+	"EXP":     fnExp,
+	"ERL":     fnErl,
+	"ERR":     fnErr,
+	"FIX":     fnFix,
+	"FORMAT$": fnFormat,
+	"INKEY$":  fnInkey,
+	"INPUT$":  fnInputStr,
+	"INSTR":   fnInstr,
+	"INT":     fnInt,
+	"LCASE$":  fnLcase,
+	"LOG":     fnLog("LOG", math.Log),
+	"LOG10":   fnLog("LOG10", math.Log10),
+	"MID$":    fnMid,
+	"PEEK":    fnPeek,
+	"POS":     fnPos,
+	"RND":     fnRnd,
+	"SEEK":    fnSeek,
+	"SIN":     trigFunc("SIN", math.Sin),
+	"SPACE$":  fnSpace,
+	"STR$":    fnStr,
+	"STRING$": fnString,
+	"TAN":     trigFunc("TAN", math.Tan),
+	"TIME$":   fnTimeStr,
+	"TIMER":   fnTimer,
+	"UCASE$":  fnUcase,
+	"VAL":     fnVal,
+}
+
+// refFunc is a function that is passed its arguments unevaluated, for
+// functions such as VARPTR that work on the variable rather than its value.
+type refFunc func(intp *Interpreter, args []Expression) (Value, error)
+
+var refBuiltins = map[string]refFunc{
+	"VARPTR":  fnVarptr,
+	"VARPTR$": fnVarptrStr,
+}
+
+// checkArgs verifies that args matches kinds, where each kind is '$' for a
+// string, '#' for a number or '?' for either.
+func checkArgs(name string, args []Value, kinds string) error {
+	if len(args) != len(kinds) {
+		return fmt.Errorf("%s: expected %d arguments got %d", name, len(kinds), len(args))
+	}
+	for i := range args {
+		if kinds[i] != '?' && args[i].IsStr != (kinds[i] == '$') {
+			return fmt.Errorf("%s: type mismatch for argument %d", name, i+1)
+		}
+	}
+	return nil
+}
+
+func fnFormat(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("FORMAT$", args, "?$"); err != nil {
+		return Value{}, err
+	}
+	s, err := formatUsing(args[1].Str, args[:1])
+	if err != nil {
+		return Value{}, err
+	}
+	return strValue(s), nil
+}
+
+func fnAsc(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("ASC", args, "$"); err != nil {
+		return Value{}, err
+	}
+	if len(args[0].Str) == 0 {
+		return Value{}, fmt.Errorf("ASC of empty string")
+	}
+	return Value{Int: int(args[0].Str[0])}, nil
+}
+
+func fnChr(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("CHR$", args, "#"); err != nil {
+		return Value{}, err
+	}
+	code := args[0].int()
+	if code < 0 || code > 255 {
+		return Value{}, fmt.Errorf("CHR$: code %d out of range 0-255", code)
+	}
+	return strValue(string([]byte{byte(code)})), nil
+}
+
+// fnMid returns length characters of a string starting at the 1 based
+// start, or the rest of the string when there is no length.
+func fnMid(_ *Interpreter, args []Value) (Value, error) {
+	kinds := "$##"
+	if len(args) == 2 {
+		kinds = "$#"
+	}
+	if err := checkArgs("MID$", args, kinds); err != nil {
+		return Value{}, err
+	}
+	s, start := args[0].Str, args[1].int()
+	if start < 1 {
+		return Value{}, fmt.Errorf("MID$: illegal start %d", start)
+	}
+	if start > len(s) {
+		return strValue(""), nil
+	}
+	s = s[start-1:]
+	if len(args) == 3 {
+		length := args[2].int()
+		if length < 0 {
+			return Value{}, fmt.Errorf("MID$: illegal length %d", length)
+		}
+		if length < len(s) {
+			s = s[:length]
+		}
+	}
+	return strValue(s), nil
+}
+
+// fnInstr returns the 1 based position of the first needle in haystack,
+// searching from start, which is 1 if it is not given, or 0 if it is not
+// found. An empty needle is found at start.
+func fnInstr(_ *Interpreter, args []Value) (Value, error) {
+	start := 1
+	if len(args) == 3 {
+		if err := checkArgs("INSTR", args, "#$$"); err != nil {
+			return Value{}, err
+		}
+		start, args = args[0].int(), args[1:]
+		if start < 1 {
+			return Value{}, fmt.Errorf("INSTR: illegal start %d", start)
+		}
+	}
+	if err := checkArgs("INSTR", args, "$$"); err != nil {
+		return Value{}, err
+	}
+	haystack, needle := args[0].Str, args[1].Str
+	if start > len(haystack) {
+		return Value{Int: 0}, nil
+	}
+	idx := strings.Index(haystack[start-1:], needle)
+	if idx == -1 {
+		return Value{Int: 0}, nil
+	}
+	return Value{Int: start + idx}, nil
+}
+
+// fnUcase returns a string in upper case. Letters outside ASCII are
+// converted too, by their Unicode case mapping, anything else is unchanged.
+func fnUcase(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("UCASE$", args, "$"); err != nil {
+		return Value{}, err
+	}
+	return strValue(strings.ToUpper(args[0].Str)), nil
+}
+
+// fnLcase returns a string in lower case, see fnUcase.
+func fnLcase(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("LCASE$", args, "$"); err != nil {
+		return Value{}, err
+	}
+	return strValue(strings.ToLower(args[0].Str)), nil
+}
+
+// fnString returns n copies of a character, given either as a string, whose
+// first character is used, or as a character code.
+func fnString(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("STRING$", args, "#?"); err != nil {
+		return Value{}, err
+	}
+	c := args[1].Str
+	if !args[1].IsStr {
+		code := args[1].int()
+		if code < 0 || code > 255 {
+			return Value{}, fmt.Errorf("STRING$: code %d out of range 0-255", code)
+		}
+		c = string([]byte{byte(code)})
+	}
+	if len(c) == 0 {
+		return Value{}, fmt.Errorf("STRING$ of empty string")
+	}
+	n := args[0].int()
+	if n < 0 {
+		n = 0
+	}
+	return strValue(strings.Repeat(c[:1], n)), nil
+}
+
+// fnSpace returns n spaces.
+func fnSpace(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("SPACE$", args, "#"); err != nil {
+		return Value{}, err
+	}
+	n := args[0].int()
+	if n < 0 {
+		n = 0
+	}
+	return strValue(strings.Repeat(" ", n)), nil
+}
+
+// fnStr formats a number the same way Value does, with a leading space
+// in place of the sign for non negative numbers.
+func fnStr(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("STR$", args, "#"); err != nil {
+		return Value{}, err
+	}
+	s := args[0].String()
+	if args[0].float() >= 0 {
+		s = " " + s
+	}
+	return strValue(s), nil
+}
+
+// fnVal parses the number at the start of a string, ignoring anything after
+// it. A string that does not start with a number is 0.
+func fnVal(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("VAL", args, "$"); err != nil {
+		return Value{}, err
+	}
+	s := strings.TrimLeft(args[0].Str, " ")
+	end := 0
+	if end < len(s) && (s[end] == '-' || s[end] == '+') {
+		end++
+	}
+	for seenDot := false; end < len(s); end++ {
+		if s[end] == '.' && !seenDot {
+			seenDot = true
+			continue
+		}
+		if !isDigit(s[end]) {
+			break
+		}
+	}
+	val, err := parseNumber(s[:end])
+	if err != nil {
+		return Value{}, nil
+	}
+	return val, nil
+}
+
+// fnLog returns a builtin for a logarithm, which is only defined for positive
+// numbers.
+func fnLog(name string, log func(float64) float64) builtinFunc {
+	return func(_ *Interpreter, args []Value) (Value, error) {
+		if err := checkArgs(name, args, "#"); err != nil {
+			return Value{}, err
+		}
+		x := args[0].float()
+		if x <= 0 {
+			return Value{}, fmt.Errorf("%s of non-positive %s", name, args[0])
+		}
+		return floatValue(log(x)), nil
+	}
+}
+
+// fnExp returns e to the power of a number.
+func fnExp(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("EXP", args, "#"); err != nil {
+		return Value{}, err
+	}
+	f := math.Exp(args[0].float())
+	if math.IsInf(f, 0) {
+		return Value{}, fmt.Errorf("EXP: overflow: %s", args[0])
+	}
+	return floatValue(f), nil
+}
+
+// roundInt rounds a number half to even, as MS-BASIC does, and checks that
+// the result fits in a signed integer of the given number of bits.
+func roundInt(name string, v Value, bits int) (Value, error) {
+	f := math.RoundToEven(v.float())
+	if max := float64(int(1)<<(bits-1) - 1); f > max || f < -max-1 {
+		return Value{}, fmt.Errorf("%s: overflow: %s", name, v)
+	}
+	return Value{Int: int(f)}, nil
+}
+
+// fnCint converts a number to an integer of the interpreter's IntSize.
+func fnCint(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("CINT", args, "#"); err != nil {
+		return Value{}, err
+	}
+	bits := intp.IntSize
+	if bits == 0 {
+		bits = 32
+	}
+	return roundInt("CINT", args[0], bits)
+}
+
+// fnClng converts a number to a 32 bit long integer, whatever the IntSize.
+func fnClng(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("CLNG", args, "#"); err != nil {
+		return Value{}, err
+	}
+	return roundInt("CLNG", args[0], 32)
+}
+
+// fnCsng converts a number to single precision. The result is the shortest
+// decimal that round trips as a float32, so CSNG(0.1) prints as 0.1.
+func fnCsng(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("CSNG", args, "#"); err != nil {
+		return Value{}, err
+	}
+	s := strconv.FormatFloat(args[0].float(), 'g', -1, 32)
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) {
+		// too big for a float32 is formatted as +Inf or -Inf
+		return Value{}, fmt.Errorf("CSNG: overflow: %s", args[0])
+	}
+	return floatValue(f), nil
+}
+
+// fnCdbl converts a number to double precision.
+func fnCdbl(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("CDBL", args, "#"); err != nil {
+		return Value{}, err
+	}
+	return floatValue(args[0].float()), nil
+}
+
+// fnFix truncates a number toward zero, so FIX(-2.7) is -2.
+func fnFix(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("FIX", args, "#"); err != nil {
+		return Value{}, err
+	}
+	if !args[0].IsFloat {
+		return args[0], nil
+	}
+	// adding 0 turns the -0 of FIX(-0.5) into 0
+	return floatValue(math.Trunc(args[0].Float) + 0), nil
+}
+
+// fnInt rounds a number down, so INT(-2.7) is -3.
+func fnInt(_ *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("INT", args, "#"); err != nil {
+		return Value{}, err
+	}
+	if !args[0].IsFloat {
+		return args[0], nil
+	}
+	return floatValue(math.Floor(args[0].Float)), nil
+}
+
+// fnTimer returns the number of seconds since midnight, by the interpreter's
+// clock, as a float.
+func fnTimer(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("TIMER", args, ""); err != nil {
+		return Value{}, err
+	}
+	now := intp.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return floatValue(now.Sub(midnight).Seconds()), nil
+}
+
+// fnTimeStr returns the time of the interpreter's clock as HH:MM:SS.
+func fnTimeStr(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("TIME$", args, ""); err != nil {
+		return Value{}, err
+	}
+	return strValue(intp.Now().Format("15:04:05")), nil
+}
@@ -0,0 +1,321 @@
+package gdbasic
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exprTest is an expression and the listing of the Value it evaluates to, so
+// strings are quoted.
+type exprTest struct {
+	expr string
+	want string
+}
+
+func testExprs(t *testing.T, tests []exprTest) {
+	t.Helper()
+	bob := NewInterpreter()
+	for _, tc := range tests {
+		val, err := eval(t, bob, tc.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.expr, err)
+			continue
+		}
+		if got := val.String(); got != tc.want {
+			t.Errorf("%s = %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func testExprErrors(t *testing.T, exprs ...string) {
+	t.Helper()
+	bob := NewInterpreter()
+	for _, expr := range exprs {
+		if val, err := eval(t, bob, expr); err == nil {
+			t.Errorf("%s = %s, expected an error", expr, val)
+		}
+	}
+}
+
+func TestChrAsc(t *testing.T) {
+	testExprs(t, []exprTest{
+		{`CHR$(65)`, `"A"`},
+		{`CHR$(48+9)`, `"9"`},
+		{`ASC("A")`, `65`},
+		{`ASC("abc")`, `97`},
+		{`ASC(CHR$(200))`, `200`},
+		{`CHR$(ASC("Z"))`, `"Z"`},
+	})
+	testExprErrors(t,
+		`CHR$(-1)`,
+		`CHR$(256)`,
+		`CHR$("A")`,
+		`ASC("")`,
+		`ASC(65)`,
+	)
+}
+
+func TestStrVal(t *testing.T) {
+	testExprs(t, []exprTest{
+		{`STR$(5)`, `" 5"`},
+		{`STR$(-5)`, `"-5"`},
+		{`STR$(0)`, `" 0"`},
+		{`STR$(2.5)`, `" 2.5"`},
+		{`VAL("42")`, `42`},
+		{`VAL("  -7")`, `-7`},
+		{`VAL("+3")`, `3`},
+		{`VAL("2.5")`, `2.5`},
+		{`VAL("12ABC")`, `12`},
+		{`VAL("1.2.3")`, `1.2`},
+		{`VAL("ABC")`, `0`},
+		{`VAL("")`, `0`},
+		{`VAL(STR$(-12))`, `-12`},
+	})
+	testExprErrors(t,
+		`STR$("A")`,
+		`VAL(1)`,
+	)
+}
+
+func TestConversions(t *testing.T) {
+	testExprs(t, []exprTest{
+		// CINT and CLNG round half to even
+		{`CINT(2.5)`, `2`},
+		{`CINT(3.5)`, `4`},
+		{`CINT(-2.5)`, `-2`},
+		{`CINT(-3.5)`, `-4`},
+		{`CINT(2.4)`, `2`},
+		{`CINT(2.6)`, `3`},
+		{`CINT(7)`, `7`},
+		{`CLNG(0.5)`, `0`},
+		{`CLNG(1.5)`, `2`},
+		{`CLNG(2147483647.4)`, `2147483647`},
+		{`CLNG(-2147483648)`, `-2147483648`},
+		{`CSNG(0.1)`, `0.1`},
+		{`CSNG(1/3)`, `0.33333334`},
+		{`CSNG(2)`, `2`},
+		{`CDBL(2)`, `2`},
+		{`CDBL(1.25)`, `1.25`},
+	})
+	testExprErrors(t,
+		`CINT(2147483647.5)`,
+		`CLNG(2147483647.5)`,
+		`CLNG(-2147483648.6)`,
+		`CSNG(1E39)`,
+		`CINT("1")`,
+		`CDBL()`,
+	)
+}
+
+func TestCintIntSize(t *testing.T) {
+	bob := NewInterpreter()
+	bob.IntSize = 16
+	for _, tc := range []struct {
+		expr     string
+		overflow bool
+	}{
+		{`CINT(32767.4)`, false},
+		{`CINT(32767.5)`, true},
+		{`CINT(-32768.5)`, false},
+		{`CINT(-32768.6)`, true},
+		// CLNG is 32 bits whatever the IntSize
+		{`CLNG(32767.5)`, false},
+	} {
+		val, err := eval(t, bob, tc.expr)
+		if tc.overflow && err == nil {
+			t.Errorf("%s = %v, expected an overflow", tc.expr, val)
+		}
+		if !tc.overflow && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.expr, err)
+		}
+	}
+}
+
+func TestFixInt(t *testing.T) {
+	testExprs(t, []exprTest{
+		{`FIX(2.7)`, `2`},
+		{`INT(2.7)`, `2`},
+		{`FIX(-2.7)`, `-2`},
+		{`INT(-2.7)`, `-3`},
+		{`FIX(-2)`, `-2`},
+		{`INT(-2)`, `-2`},
+		{`FIX(0.5)`, `0`},
+		{`INT(-0.5)`, `-1`},
+		{`FIX(-0.5)`, `0`},
+	})
+	testExprErrors(t, `FIX("1")`, `FIX(1, 2)`)
+}
+
+func TestTimer(t *testing.T) {
+	tests := []struct {
+		now   time.Time
+		timer string
+		time  string
+	}{
+		{time.Date(2024, 3, 1, 13, 45, 30, 500e6, time.UTC), "49530.5", "13:45:30"},
+		{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "0", "00:00:00"},
+		{time.Date(2024, 3, 1, 9, 5, 7, 0, time.UTC), "32707", "09:05:07"},
+		{time.Date(2024, 3, 1, 23, 59, 59, 0, time.FixedZone("X", -5*3600)), "86399", "23:59:59"},
+	}
+	for _, tt := range tests {
+		bob, out := newTestInterpreter(t, `10 PRINT TIMER; " "; TIME$`)
+		bob.Now = func() time.Time { return tt.now }
+		if err := bob.Run(); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if want := tt.timer + " " + tt.time + "\n"; out.String() != want {
+			t.Errorf("%v: printed %q, want %q", tt.now, out.String(), want)
+		}
+	}
+	testExprErrors(t, `TIMER(1)`, `TIME$(1)`)
+}
+
+func TestTimerElapsed(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	bob, out := newTestInterpreter(t,
+		`10 LET T=TIMER`,
+		`20 LET D=TIMER-T`,
+		`30 PRINT D`,
+	)
+	bob.Now = func() time.Time {
+		now = now.Add(250 * time.Millisecond)
+		return now
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.String() != "0.25\n" {
+		t.Errorf("printed %q, want %q", out.String(), "0.25\n")
+	}
+}
+
+func TestStringSpace(t *testing.T) {
+	testExprs(t, []exprTest{
+		// a one character string
+		{`STRING$(3, "*")`, `"***"`},
+		{`STRING$(5, "-")`, `"-----"`},
+		// only the first character of a longer string is used
+		{`STRING$(2, "AB")`, `"AA"`},
+		// a character code
+		{`STRING$(3, 42)`, `"***"`},
+		{`STRING$(2, 65.9)`, `"AA"`},
+		{`STRING$(1, 0)`, `"` + "\x00" + `"`},
+		{`STRING$(0, "*")`, `""`},
+		{`STRING$(-2, "*")`, `""`},
+		{`STRING$(-2, 42)`, `""`},
+		{`SPACE$(3)`, `"   "`},
+		{`SPACE$(0)`, `""`},
+		{`SPACE$(-1)`, `""`},
+		{`SPACE$(2.7)`, `"  "`},
+		{`"[" + SPACE$(2) + STRING$(2, "=") + "]"`, `"[  ==]"`},
+	})
+	testExprErrors(t,
+		`STRING$(3, "")`,
+		`STRING$(3, 256)`,
+		`STRING$(3, -1)`,
+		`STRING$("A", "*")`,
+		`STRING$(3)`,
+		`SPACE$("A")`,
+		`SPACE$()`,
+	)
+}
+
+func TestInstr(t *testing.T) {
+	testExprs(t, []exprTest{
+		// found
+		{`INSTR("HELLO", "L")`, `3`},
+		{`INSTR("HELLO", "HELLO")`, `1`},
+		{`INSTR("HELLO", "LO")`, `4`},
+		// not found
+		{`INSTR("HELLO", "Z")`, `0`},
+		{`INSTR("HELLO", "l")`, `0`},
+		{`INSTR("HI", "HIGH")`, `0`},
+		{`INSTR("", "A")`, `0`},
+		// start offset
+		{`INSTR(4, "HELLO", "L")`, `4`},
+		{`INSTR(5, "HELLO", "L")`, `0`},
+		{`INSTR(1, "ABCABC", "ABC")`, `1`},
+		{`INSTR(2, "ABCABC", "ABC")`, `4`},
+		{`INSTR(9, "HELLO", "L")`, `0`},
+		// an empty needle is found at the start
+		{`INSTR("HELLO", "")`, `1`},
+		{`INSTR(3, "HELLO", "")`, `3`},
+		{`INSTR(5, "HELLO", "")`, `5`},
+		{`INSTR(6, "HELLO", "")`, `0`},
+	})
+	testExprErrors(t,
+		`INSTR(0, "HELLO", "L")`,
+		`INSTR(-1, "HELLO", "L")`,
+		`INSTR("HELLO")`,
+		`INSTR(1, "HELLO")`,
+		`INSTR("HELLO", 1)`,
+		`INSTR("1", "HELLO", "L")`,
+		`INSTR(1, 2, 3, 4)`,
+	)
+}
+
+func TestCase(t *testing.T) {
+	testExprs(t, []exprTest{
+		{`UCASE$("Hello, World 1!")`, `"HELLO, WORLD 1!"`},
+		{`LCASE$("Hello, World 1!")`, `"hello, world 1!"`},
+		{`UCASE$("")`, `""`},
+		{`LCASE$("123 +-*")`, `"123 +-*"`},
+		// letters outside ASCII use their Unicode case mapping, a rune at a
+		// time, so ß has no upper case
+		{`UCASE$("straße café")`, `"STRAßE CAFÉ"`},
+		{`LCASE$("ÀÉÎ ΣΑΣ")`, `"àéî σασ"`},
+		{`UCASE$(LCASE$("MiXeD"))`, `"MIXED"`},
+		{`UCASE$("yes") = "YES"`, `-1`},
+	})
+	testExprErrors(t, `UCASE$(1)`, `LCASE$(1)`, `UCASE$()`, `LCASE$("A", "B")`)
+
+	got := runProgram(t,
+		`10 LET A$="MiXeD"`,
+		`20 PRINT UCASE$(A$); LCASE$(A$)`,
+	)
+	if want := "MIXEDmixed\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestLogExp(t *testing.T) {
+	bob := NewInterpreter()
+	for _, x := range []float64{1, 2, 0.5, 10, 1e-6, 12345.678, 1e30} {
+		// each comes back to x
+		for _, expr := range []string{
+			fmt.Sprintf(`EXP(LOG(%v))`, x),
+			fmt.Sprintf(`10^LOG10(%v)`, x),
+			fmt.Sprintf(`EXP(LOG(%v)/2)^2`, x),
+		} {
+			val, err := eval(t, bob, expr)
+			if err != nil {
+				t.Errorf("%s: %v", expr, err)
+				continue
+			}
+			if !val.IsFloat || math.Abs(val.Float-x) > 1e-9*x {
+				t.Errorf("%s = %#v, want about %v", expr, val, x)
+			}
+		}
+	}
+	testExprs(t, []exprTest{
+		{`LOG(1)`, `0`},
+		{`EXP(0)`, `1`},
+		{`LOG10(1000)`, `3`},
+		{`LOG10(0.01)`, `-2`},
+		{`INT(EXP(1)*1000)`, `2718`},
+		{`INT(LOG(100)/LOG(10)+0.5)`, `2`},
+	})
+}
+
+func TestLogErrors(t *testing.T) {
+	for _, expr := range []string{`LOG(0)`, `LOG(-1)`, `LOG10(0)`, `LOG10(-0.5)`} {
+		_, err := eval(t, NewInterpreter(), expr)
+		if err == nil || !strings.Contains(err.Error(), "of non-positive") {
+			t.Errorf("%s: got %v, want a non-positive error", expr, err)
+		}
+	}
+	testExprErrors(t, `EXP(1000)`, `LOG("A")`, `EXP("A")`, `LOG()`, `LOG10(1, 2)`)
+}
@@ -0,0 +1,3 @@
+module github.com/gdey/gdbasic
+
+go 1.21
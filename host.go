@@ -0,0 +1,109 @@
+package gdbasic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostFunc is a Go function that can be called from BASIC.
+type HostFunc func(args []Value) (Value, error)
+
+// HostFuncEx is a Go function that can be called from BASIC and is given the
+// interpreter, so it can read and set the program's variables.
+type HostFuncEx func(intp *Interpreter, args []Value) (Value, error)
+
+// RegisterFunc makes fn callable from BASIC expressions as name, for example
+// `LET A=DOUBLE(21)`. The name can not be that of a built in function or start
+// with FN, which is reserved for DEF FN.
+func (bob *Interpreter) RegisterFunc(name string, fn HostFunc) error {
+	return bob.RegisterFuncEx(name, func(_ *Interpreter, args []Value) (Value, error) {
+		return fn(args)
+	})
+}
+
+// RegisterFuncEx is RegisterFunc for a function that needs the interpreter.
+func (bob *Interpreter) RegisterFuncEx(name string, fn HostFuncEx) error {
+	name = strings.ToUpper(name)
+	if _, ok := builtins[name]; ok {
+		return fmt.Errorf("can not register built in function %s", name)
+	}
+	if _, ok := refBuiltins[name]; ok {
+		return fmt.Errorf("can not register built in function %s", name)
+	}
+	if strings.HasPrefix(name, "FN") {
+		return fmt.Errorf("can not register %s, FN is reserved for DEF FN", name)
+	}
+	if bob.hostFuncs == nil {
+		bob.hostFuncs = map[string]HostFuncEx{}
+	}
+	bob.hostFuncs[name] = fn
+	return nil
+}
+
+// usrSlot returns the slot number of a USR function name, USR or USR0 to
+// USR9, where USR is the same as USR0.
+func usrSlot(name string) (int, bool) {
+	if !strings.HasPrefix(name, "USR") {
+		return 0, false
+	}
+	switch digits := name[len("USR"):]; {
+	case digits == "":
+		return 0, true
+	case len(digits) == 1 && isDigit(digits[0]):
+		return int(digits[0] - '0'), true
+	}
+	return 0, false
+}
+
+// callUsr calls the machine language routine of a USR slot. Here there is no
+// machine code; if the slot was defined with the name of a registered
+// function, that function is called, otherwise the function registered as USR
+// is called no matter what address was defined.
+func (bob *Interpreter) callUsr(slot int, args []Value) (Value, error) {
+	name := "USR"
+	if def := bob.usr[slot]; def.IsStr {
+		name = strings.ToUpper(def.Str)
+	}
+	fn, ok := bob.hostFuncs[name]
+	if !ok {
+		return Value{}, fmt.Errorf("USR%d: no function registered as %s", slot, name)
+	}
+	return fn(bob, args)
+}
+
+// DefUsrInstruction sets the routine a USR slot calls, either a machine
+// language address, for old programs, or the name of a registered function.
+type DefUsrInstruction struct {
+	Slot    int
+	Routine Expression
+}
+
+func (dui DefUsrInstruction) Execute(intp *Interpreter) error {
+	val, err := dui.Routine.Eval(intp)
+	if err != nil {
+		return err
+	}
+	intp.usr[dui.Slot] = val
+	return nil
+}
+
+func (dui DefUsrInstruction) String() string {
+	return fmt.Sprintf("DEF USR%d=%s", dui.Slot, dui.Routine)
+}
+
+func NewDefUsrInstruction(_ int, remainder string) (*DefUsrInstruction, error) {
+	// DEF USR0 = "DOUBLE"
+	idx := strings.Index(remainder, "=")
+	if idx == -1 {
+		return nil, fmt.Errorf("invalid def usr statement")
+	}
+	slot, ok := usrSlot(strings.TrimSpace(remainder[:idx]))
+	if !ok {
+		return nil, fmt.Errorf("def usr: bad name `%s`", strings.TrimSpace(remainder[:idx]))
+	}
+	routine, err := ParseExpression(remainder[idx+1:])
+	if err != nil {
+		return nil, err
+	}
+	return &DefUsrInstruction{Slot: slot, Routine: routine}, nil
+}
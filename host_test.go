@@ -0,0 +1,139 @@
+package gdbasic
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// double returns twice its one numeric argument.
+func double(args []Value) (Value, error) {
+	if len(args) != 1 || args[0].IsStr {
+		return Value{}, fmt.Errorf("DOUBLE: expected 1 number")
+	}
+	return Value{Int: args[0].Int * 2}, nil
+}
+
+func TestRegisterFunc(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A=DOUBLE(21)`,
+		`20 PRINT A; " "; double(DOUBLE(1))`,
+	)
+	if err := bob.RegisterFunc("double", double); err != nil {
+		t.Fatalf("RegisterFunc: %v", err)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got, want := out.String(), "42 4\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+
+	for _, name := range []string{"chr$", "FNA", "VARPTR"} {
+		if err := bob.RegisterFunc(name, double); err == nil {
+			t.Errorf("RegisterFunc(%q): expected an error", name)
+		}
+	}
+}
+
+func TestUsr(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{
+			name:    "USR is USR0",
+			program: []string{`10 PRINT USR(21); " "; USR0(21)`},
+			want:    "42 42\n",
+		},
+		{
+			name:    "machine language address",
+			program: []string{`10 DEF USR1=&HC000`, `20 PRINT USR1(21)`},
+			want:    "42\n",
+		},
+		{
+			name:    "registered function by name",
+			program: []string{`10 DEF USR2="DOUBLE"`, `20 PRINT USR2(5)`},
+			want:    "10\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t, tc.program...)
+			if err := bob.RegisterFunc("USR", double); err != nil {
+				t.Fatalf("RegisterFunc: %v", err)
+			}
+			if err := bob.RegisterFunc("DOUBLE", double); err != nil {
+				t.Fatalf("RegisterFunc: %v", err)
+			}
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("printed %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUsrErrors(t *testing.T) {
+	for _, program := range [][]string{
+		{`10 PRINT USR(1)`},
+		{`10 DEF USR3="MISSING"`, `20 PRINT USR3(1)`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		if err := bob.Run(); err == nil {
+			t.Errorf("%q: expected an error", program)
+		}
+	}
+	for _, line := range []string{`DEF USR10=1`, `DEF USRX=1`, `DEF USR0`} {
+		if _, err := parseInstruction(10, line); err == nil {
+			t.Errorf("%s: expected an error", line)
+		}
+	}
+}
+
+func TestRegisterFuncEx(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET SCORE=40`,
+		`20 PRINT BONUS(2); " "; SCORE`,
+	)
+	// BONUS reads SCORE, adds its argument to it and stores the result
+	err := bob.RegisterFuncEx("BONUS", func(intp *Interpreter, args []Value) (Value, error) {
+		score, ok := intp.GetVar("SCORE")
+		if !ok {
+			return Value{}, fmt.Errorf("BONUS: no SCORE")
+		}
+		val := Value{Int: score.Int + args[0].Int}
+		return val, intp.SetVar("SCORE", val)
+	})
+	if err != nil {
+		t.Fatalf("RegisterFuncEx: %v", err)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got, want := out.String(), "42 42\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+	if err := bob.RegisterFuncEx("FNX", nil); err == nil {
+		t.Error("RegisterFuncEx(FNX): expected an error")
+	}
+}
+
+func TestRegisterFuncClone(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 PRINT DOUBLE(4)`)
+	if err := bob.RegisterFunc("DOUBLE", double); err != nil {
+		t.Fatalf("RegisterFunc: %v", err)
+	}
+	c := bob.Clone()
+	var out bytes.Buffer
+	c.Output = &out
+	if err := c.Run(); err != nil {
+		t.Fatalf("clone Run: %v", err)
+	}
+	if got := out.String(); got != "8\n" {
+		t.Errorf("clone printed %q, want %q", got, "8\n")
+	}
+}
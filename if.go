@@ -0,0 +1,143 @@
+package gdbasic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IfInstruction runs Then when Cond is not zero and Else, if there is one,
+// when it is. Each branch is either a GOTO, written as just the line number,
+// or a statement. With no Else a false condition goes on to the next line.
+type IfInstruction struct {
+	Cond Expression
+	Then Instructioner
+	// Else is nil when there is no ELSE.
+	Else Instructioner
+}
+
+func (ii IfInstruction) Execute(intp *Interpreter) error {
+	val, err := ii.Cond.Eval(intp)
+	if err != nil {
+		return err
+	}
+	if val.IsStr {
+		return fmt.Errorf("type mismatch: IF %s", ii.Cond)
+	}
+	if val.float() != 0 {
+		return ii.Then.Execute(intp)
+	}
+	if ii.Else != nil {
+		return ii.Else.Execute(intp)
+	}
+	return nil
+}
+
+// branchString lists a branch, jumps are written as just the line number.
+func branchString(ins Instructioner) string {
+	if jmp, ok := ins.(JumpInstruction); ok {
+		return strconv.Itoa(int(jmp))
+	}
+	return fmt.Sprint(ins)
+}
+
+func (ii IfInstruction) String() string {
+	s := fmt.Sprintf("IF %s THEN %s", ii.Cond, branchString(ii.Then))
+	if ii.Else != nil {
+		s += " ELSE " + branchString(ii.Else)
+	}
+	return s
+}
+
+func (ii IfInstruction) LineRefs() []int {
+	var refs []int
+	for _, branch := range []Instructioner{ii.Then, ii.Else} {
+		if lr, ok := branch.(LineReferencer); ok {
+			refs = append(refs, lr.LineRefs()...)
+		}
+	}
+	return refs
+}
+
+func (ii IfInstruction) RenumberRefs(lines map[int]int) Instructioner {
+	if lr, ok := ii.Then.(LineReferencer); ok {
+		ii.Then = lr.RenumberRefs(lines)
+	}
+	if lr, ok := ii.Else.(LineReferencer); ok {
+		ii.Else = lr.RenumberRefs(lines)
+	}
+	return ii
+}
+
+func NewIfInstruction(lineNumber int, remainder string) (*IfInstruction, error) {
+	// IF A>0 THEN PRINT "POS" ELSE 200
+	cond, rest, ok := cutKeyword(remainder, "THEN")
+	if !ok {
+		return nil, fmt.Errorf("if is missing THEN")
+	}
+	expr, err := ParseExpression(cond)
+	if err != nil {
+		return nil, err
+	}
+	ii := &IfInstruction{Cond: expr}
+	then, els, hasElse := cutElse(rest)
+	col := len(remainder) - len(rest)
+	if ii.Then, err = parseBranch(lineNumber, then, col); err != nil {
+		return nil, err
+	}
+	if hasElse {
+		col += len(then) + len("ELSE")
+		if ii.Else, err = parseBranch(lineNumber, els, col); err != nil {
+			return nil, err
+		}
+	}
+	return ii, nil
+}
+
+// cutElse slices the branches of an IF around the ELSE that belongs to it.
+// An ELSE goes with the nearest IF before it that does not have one yet, so
+// in `IF A THEN IF B THEN 10 ELSE 20` the ELSE is the inner IF's.
+func cutElse(s string) (then, els string, ok bool) {
+	open := 0
+	for offset := 0; ; {
+		before, after, found := cutKeyword(s[offset:], "ELSE")
+		if !found {
+			return s, "", false
+		}
+		open += countKeyword(before, "IF")
+		if open == 0 {
+			return s[:offset+len(before)], after, true
+		}
+		// this ELSE belongs to a nested IF
+		open--
+		offset += len(before) + len("ELSE")
+	}
+}
+
+// countKeyword returns how many times cutKeyword finds kw in s.
+func countKeyword(s, kw string) int {
+	n := 0
+	for {
+		var ok bool
+		if _, s, ok = cutKeyword(s, kw); !ok {
+			return n
+		}
+		n++
+	}
+}
+
+// parseBranch parses a THEN or ELSE branch, a line number or a statement.
+// col is where the branch starts in the IF's remainder.
+func parseBranch(lineNumber int, branch string, col int) (Instructioner, error) {
+	trimmed := strings.TrimLeft(branch, " \t")
+	col += len(branch) - len(trimmed)
+	branch = strings.TrimSpace(trimmed)
+	if branch == "" {
+		return nil, parseErrorf(col, "if has an empty branch")
+	}
+	if isDigit(branch[0]) {
+		return NewJumpInstruction(lineNumber, branch)
+	}
+	ins, err := parseInstruction(lineNumber, branch)
+	return ins, shiftParseError(err, col)
+}
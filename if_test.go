@@ -0,0 +1,146 @@
+package gdbasic
+
+import "testing"
+
+func TestIf(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{
+			name:    "statement THEN",
+			program: []string{`10 LET A=1`, `20 IF A>0 THEN PRINT "POS" ELSE PRINT "NEG"`},
+			want:    "POS\n",
+		},
+		{
+			name:    "statement ELSE",
+			program: []string{`10 LET A=-1`, `20 IF A>0 THEN PRINT "POS" ELSE PRINT "NEG"`},
+			want:    "NEG\n",
+		},
+		{
+			name: "line number ELSE",
+			program: []string{
+				`10 LET X=2`,
+				`20 IF X=1 THEN 100 ELSE 200`,
+				`100 PRINT "ONE"`,
+				`110 GOTO 210`,
+				`200 PRINT "OTHER"`,
+				`210 REM`,
+			},
+			want: "OTHER\n",
+		},
+		{
+			name: "line number THEN",
+			program: []string{
+				`10 LET X=1`,
+				`20 IF X=1 THEN 100 ELSE 200`,
+				`100 PRINT "ONE"`,
+				`110 GOTO 210`,
+				`200 PRINT "OTHER"`,
+				`210 REM`,
+			},
+			want: "ONE\n",
+		},
+		{
+			name:    "false without ELSE falls through",
+			program: []string{`10 IF 0 THEN PRINT "NO"`, `20 PRINT "NEXT"`},
+			want:    "NEXT\n",
+		},
+		{
+			name:    "ELSE in a string",
+			program: []string{`10 IF 0 THEN PRINT "IF ELSE" ELSE PRINT "ELSE"`},
+			want:    "ELSE\n",
+		},
+		{
+			name:    "ELSE IF",
+			program: []string{`10 LET A=2`, `20 IF A=1 THEN PRINT "A" ELSE IF A=2 THEN PRINT "B" ELSE PRINT "C"`},
+			want:    "B\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runProgram(t, tc.program...); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNestedIfElse(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		// the ELSE goes with the nearest IF
+		{`10 IF 1 THEN IF 0 THEN PRINT "A" ELSE PRINT "B"`, "B\n"},
+		{`10 IF 1 THEN IF 1 THEN PRINT "A" ELSE PRINT "B"`, "A\n"},
+		{`10 IF 0 THEN IF 0 THEN PRINT "A" ELSE PRINT "B"`, ""},
+		// a second ELSE goes with the outer IF
+		{`10 IF 1 THEN IF 0 THEN PRINT "A" ELSE PRINT "B" ELSE PRINT "C"`, "B\n"},
+		{`10 IF 0 THEN IF 1 THEN PRINT "A" ELSE PRINT "B" ELSE PRINT "C"`, "C\n"},
+	}
+	for _, tc := range tests {
+		if got := runProgram(t, tc.line); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestCutElse(t *testing.T) {
+	tests := []struct {
+		in        string
+		then, els string
+		ok        bool
+	}{
+		{` 10`, ` 10`, ``, false},
+		{` 10 ELSE 20`, ` 10 `, ` 20`, true},
+		{` IF B THEN 10 ELSE 20`, ` IF B THEN 10 ELSE 20`, ``, false},
+		{` IF B THEN 10 ELSE 20 ELSE 30`, ` IF B THEN 10 ELSE 20 `, ` 30`, true},
+		{` PRINT "ELSE" ELSE 30`, ` PRINT "ELSE" `, ` 30`, true},
+		{` PRINT "IF" ELSE 30`, ` PRINT "IF" `, ` 30`, true},
+		{` LET ELSEWHERE=1`, ` LET ELSEWHERE=1`, ``, false},
+	}
+	for _, tc := range tests {
+		then, els, ok := cutElse(tc.in)
+		if then != tc.then || els != tc.els || ok != tc.ok {
+			t.Errorf("cutElse(%q) = %q, %q, %v, want %q, %q, %v", tc.in, then, els, ok, tc.then, tc.els, tc.ok)
+		}
+	}
+}
+
+func TestIfParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		err  bool
+	}{
+		{line: `IF A>0 THEN PRINT "POS" ELSE PRINT "NEG"`, want: `IF A>0 THEN PRINT"POS" ELSE PRINT"NEG"`},
+		{line: `IF X=1 THEN 100 ELSE 200`, want: `IF X=1 THEN 100 ELSE 200`},
+		{line: `IF 1 THEN IF 0 THEN 10 ELSE 20`, want: `IF 1 THEN IF 0 THEN 10 ELSE 20`},
+		{line: `IF A>0 PRINT "POS"`, err: true},
+		{line: `IF A>0 THEN`, err: true},
+		{line: `IF A>0 THEN 10 ELSE`, err: true},
+	}
+	for _, tc := range tests {
+		ins, err := parseInstruction(10, tc.line)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.line, err)
+			continue
+		}
+		if got := ins.String(); got != tc.want {
+			t.Errorf("%s: listed as %q, want %q", tc.line, got, tc.want)
+		}
+	}
+	// the inner IF has the ELSE, the outer one does not
+	ins, _ := parseInstruction(10, `IF 1 THEN IF 0 THEN 10 ELSE 20`)
+	if outer := ins.(*IfInstruction); outer.Else != nil {
+		t.Errorf("outer IF has ELSE %v", outer.Else)
+	}
+}
@@ -0,0 +1,267 @@
+package gdbasic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Terminal controls whether the characters typed at the terminal are echoed
+// back, so that INPUT NOECHO can be used to enter passwords.
+type Terminal interface {
+	SetEcho(on bool) error
+}
+
+// InputInstruction reads a line from the interpreter's Input and assigns the
+// comma separated values on it to the variables. If too few values are typed
+// the user is prompted with "??" for the rest, extra values are ignored with
+// a warning. With NoEcho set the typed characters are not echoed by the
+// Terminal.
+//
+// The prompt is followed by the interpreter's InputPromptSuffix, unless the
+// prompt was separated from the variables with a comma instead of a
+// semicolon.
+type InputInstruction struct {
+	Prompt         string
+	SuppressSuffix bool
+	VarNames       []string
+	NoEcho         bool
+}
+
+func (ii InputInstruction) Execute(intp *Interpreter) error {
+	for _, name := range ii.VarNames {
+		if err := intp.checkWritable(name); err != nil {
+			return err
+		}
+	}
+	if ii.NoEcho && !intp.batch && intp.Terminal != nil {
+		if err := intp.Terminal.SetEcho(false); err != nil {
+			return err
+		}
+		defer intp.Terminal.SetEcho(true)
+	}
+	prompt := ii.Prompt
+	if !ii.SuppressSuffix {
+		prompt += intp.InputPromptSuffix
+	}
+	var fields []string
+	for len(fields) < len(ii.VarNames) {
+		line, err := intp.promptLine(prompt, ii.NoEcho)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, splitOutsideQuotes(line, ",")...)
+		prompt = "?" + intp.InputPromptSuffix
+	}
+	for i, name := range ii.VarNames {
+		field := strings.TrimSpace(fields[i])
+		if IsString(field) {
+			field = getString(field)
+		}
+		val, err := parseInputValue(name, field)
+		if err != nil {
+			return err
+		}
+		if err := intp.setVariable(name, val); err != nil {
+			return err
+		}
+	}
+	if len(fields) > len(ii.VarNames) {
+		return intp.screen.write(intp.output(), "?Extra ignored\n")
+	}
+	return nil
+}
+
+// promptLine prints the prompt and reads a line, in batch mode the prompt is
+// not printed.
+func (bob *Interpreter) promptLine(prompt string, noEcho bool) (string, error) {
+	if bob.batch {
+		return bob.readLine()
+	}
+	if err := bob.screen.write(bob.output(), prompt); err != nil {
+		return "", err
+	}
+	line, err := bob.readLine()
+	if err != nil {
+		return "", err
+	}
+	if noEcho {
+		// the newline typed by the user was not echoed either
+		if err = bob.screen.write(bob.output(), "\n"); err != nil {
+			return "", err
+		}
+	}
+	// the user's enter key moved the cursor to the start of the line
+	bob.screen.column = 0
+	return line, nil
+}
+
+func (ii InputInstruction) String() string {
+	var buf strings.Builder
+	buf.WriteString("INPUT ")
+	if ii.NoEcho {
+		buf.WriteString("NOECHO ")
+	}
+	if ii.Prompt != "" || ii.SuppressSuffix {
+		buf.WriteString(strValue(ii.Prompt).String())
+		if ii.SuppressSuffix {
+			buf.WriteRune(',')
+		} else {
+			buf.WriteRune(';')
+		}
+	}
+	buf.WriteString(strings.Join(ii.VarNames, ", "))
+	return buf.String()
+}
+
+func NewInputInstruction(_ int, remainder string) (*InputInstruction, error) {
+	// INPUT [NOECHO] ["prompt"{;|,}] A[, B$...]
+	ii := new(InputInstruction)
+	if strings.HasPrefix(remainder, "NOECHO") {
+		ii.NoEcho = true
+		remainder = strings.TrimSpace(remainder[len("NOECHO"):])
+	}
+	if prompt, rest, ok := cutQuoted(remainder); ok {
+		rest = strings.TrimSpace(rest)
+		if rest == "" || (rest[0] != ';' && rest[0] != ',') {
+			return nil, fmt.Errorf("input prompt must be followed by `;` or `,`")
+		}
+		ii.Prompt = prompt
+		ii.SuppressSuffix = rest[0] == ','
+		remainder = rest[1:]
+	}
+	for _, name := range strings.Split(remainder, ",") {
+		name = varName(name)
+		if name == "" {
+			return nil, fmt.Errorf("input is missing a variable")
+		}
+		ii.VarNames = append(ii.VarNames, name)
+	}
+	return ii, nil
+}
+
+// inputReader returns a buffered reader for Input.
+func (bob *Interpreter) inputReader() *bufio.Reader {
+	if bob.input == nil || bob.inputSource != bob.Input {
+		bob.input = bufio.NewReader(bob.Input)
+		bob.inputSource = bob.Input
+	}
+	return bob.input
+}
+
+// QueueInput puts the interpreter in batch mode, where INPUT does not prompt
+// and takes its values from the queue instead of reading Input. It is an
+// error for INPUT to be run once the queue is empty.
+func (bob *Interpreter) QueueInput(vals ...string) {
+	bob.batch = true
+	bob.inputQueue = append(bob.inputQueue, vals...)
+}
+
+// readLine reads a line from Input without the line ending, or in batch mode
+// the next value from the queue.
+func (bob *Interpreter) readLine() (string, error) {
+	if bob.batch {
+		if len(bob.inputQueue) == 0 {
+			return "", fmt.Errorf("input queue is empty")
+		}
+		line := bob.inputQueue[0]
+		bob.inputQueue = bob.inputQueue[1:]
+		return line, nil
+	}
+	line, err := bob.inputReader().ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		if err == io.EOF {
+			return "", fmt.Errorf("input past end")
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// parseInputValue converts typed text into a value for the variable name,
+// string variables end in `$` everything else is a number.
+func parseInputValue(varName, text string) (Value, error) {
+	if isStringVar(varName) {
+		return strValue(text), nil
+	}
+	return parseNumber(text)
+}
+
+// fnInkey reads a single byte from Input, returning an empty string when
+// there is no more input. Input is read as is, so to get key presses without
+// waiting for enter the caller must put the terminal into raw mode. In batch
+// mode no key is ever pressed.
+func fnInkey(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("INKEY$", args, ""); err != nil {
+		return Value{}, err
+	}
+	if intp.batch {
+		return strValue(""), nil
+	}
+	b, err := intp.inputReader().ReadByte()
+	if err == io.EOF {
+		return strValue(""), nil
+	}
+	if err != nil {
+		return Value{}, err
+	}
+	return strValue(string([]byte{b})), nil
+}
+
+// fnInputStr reads exactly n characters, from Input or, given a file number,
+// from that channel. Line endings are read like any other character. In batch
+// mode the characters come from the queued input, each value followed by a
+// newline.
+func fnInputStr(intp *Interpreter, args []Value) (Value, error) {
+	if len(args) == 2 {
+		if err := checkArgs("INPUT$", args, "##"); err != nil {
+			return Value{}, err
+		}
+	} else if err := checkArgs("INPUT$", args, "#"); err != nil {
+		return Value{}, err
+	}
+	n := args[0].int()
+	if n < 0 || n > 255 {
+		return Value{}, fmt.Errorf("INPUT$: bad length %d", n)
+	}
+	var r io.Reader
+	switch {
+	case len(args) == 2:
+		ch, err := intp.channel(args[1].int())
+		if err != nil {
+			return Value{}, err
+		}
+		r = ch.file
+	case intp.batch:
+		var buf strings.Builder
+		for buf.Len() < n {
+			if len(intp.inputQueue) == 0 {
+				return Value{}, fmt.Errorf("input past end")
+			}
+			val := intp.inputQueue[0] + "\n"
+			if need := n - buf.Len(); need < len(val) {
+				// the rest of a partly read value stays queued
+				buf.WriteString(val[:need])
+				intp.inputQueue[0] = val[need : len(val)-1]
+				break
+			}
+			buf.WriteString(val)
+			intp.inputQueue = intp.inputQueue[1:]
+		}
+		return strValue(buf.String()), nil
+	default:
+		r = intp.inputReader()
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return Value{}, fmt.Errorf("input past end")
+		}
+		return Value{}, err
+	}
+	return strValue(string(buf)), nil
+}
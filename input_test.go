@@ -0,0 +1,299 @@
+package gdbasic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeTerminal records the echo settings it is given.
+type fakeTerminal struct {
+	echo []bool
+}
+
+func (ft *fakeTerminal) SetEcho(on bool) error {
+	ft.echo = append(ft.echo, on)
+	return nil
+}
+
+func TestInputNoEcho(t *testing.T) {
+	bob, out := newTestInterpreter(t, `10 INPUT NOECHO "PASSWORD"; P$`)
+	bob.Input = strings.NewReader("secret\n")
+	term := new(fakeTerminal)
+	bob.Terminal = term
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := bob.Variables["P$"].Str; got != "secret" {
+		t.Errorf("P$ = %q, want %q", got, "secret")
+	}
+	if want := []bool{false, true}; !reflect.DeepEqual(term.echo, want) {
+		t.Errorf("SetEcho called with %v, want %v", term.echo, want)
+	}
+	// the newline the user typed was not echoed, so it is printed
+	if got, want := out.String(), "PASSWORD? \n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestInputEcho(t *testing.T) {
+	bob, out := newTestInterpreter(t, `10 INPUT "NAME"; N$`)
+	bob.Input = strings.NewReader("bob\n")
+	term := new(fakeTerminal)
+	bob.Terminal = term
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(term.echo) != 0 {
+		t.Errorf("SetEcho called with %v for INPUT without NOECHO", term.echo)
+	}
+	if got, want := out.String(), "NAME? "; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestInputNoEchoWithoutTerminal(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 INPUT NOECHO P$`)
+	bob.Input = strings.NewReader("secret\n")
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := bob.Variables["P$"].Str; got != "secret" {
+		t.Errorf("P$ = %q, want %q", got, "secret")
+	}
+}
+
+func TestInputNoEchoParse(t *testing.T) {
+	ins, err := parseInstruction(10, `INPUT NOECHO "PIN"; P$`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := ins.String(), `INPUT NOECHO "PIN";P$`; got != want {
+		t.Errorf("listed as %q, want %q", got, want)
+	}
+}
+
+func TestInputPromptSuffix(t *testing.T) {
+	if got := NewInterpreter().InputPromptSuffix; got != "? " {
+		t.Errorf("default InputPromptSuffix = %q, want %q", got, "? ")
+	}
+	tests := []struct {
+		name   string
+		line   string
+		suffix string
+		input  string
+		want   string
+	}{
+		{"default", `10 INPUT "AGE"; A`, "? ", "1\n", "AGE? "},
+		{"custom", `10 INPUT "AGE"; A`, "> ", "1\n", "AGE> "},
+		{"empty", `10 INPUT "AGE"; A`, "", "1\n", "AGE"},
+		{"comma suppresses it", `10 INPUT "AGE: ", A`, "> ", "1\n", "AGE: "},
+		{"no prompt", `10 INPUT A`, "> ", "1\n", "> "},
+		{"asking again", `10 INPUT "A, B"; A, B`, "> ", "1\n2\n", "A, B> ?> "},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t, tc.line)
+			bob.Input = strings.NewReader(tc.input)
+			bob.InputPromptSuffix = tc.suffix
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("output = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInkey(t *testing.T) {
+	program := []string{
+		`10 LET A$=INKEY$`,
+		`20 LET B$=INKEY$`,
+		`30 LET C$=INKEY$`,
+	}
+	tests := []struct {
+		name    string
+		input   string
+		batch   bool
+		a, b, c string
+	}{
+		{name: "a byte at a time", input: "xy", a: "x", b: "y", c: ""},
+		{name: "newlines are keys too", input: "\nz", a: "\n", b: "z", c: ""},
+		{name: "no input", input: "", a: "", b: "", c: ""},
+		{name: "batch mode", input: "xy", batch: true, a: "", b: "", c: ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, program...)
+			bob.Input = strings.NewReader(tc.input)
+			if tc.batch {
+				bob.QueueInput("q")
+			}
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			for name, want := range map[string]string{"A$": tc.a, "B$": tc.b, "C$": tc.c} {
+				if got := bob.Variables[name].Str; got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueInput(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 INPUT "AGE"; A`,
+		`20 INPUT NOECHO "NAME"; N$`,
+		`30 PRINT N$; A`,
+	)
+	bob.Input = strings.NewReader("not read\n")
+	term := new(fakeTerminal)
+	bob.Terminal = term
+	bob.QueueInput("42", "BOB")
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// no prompts are printed and echo is never touched
+	if got, want := out.String(), "BOB42\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if len(term.echo) != 0 {
+		t.Errorf("SetEcho called with %v in batch mode", term.echo)
+	}
+}
+
+func TestQueueInputEmpty(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 INPUT A, B`)
+	bob.QueueInput("1")
+	if err := bob.Run(); err == nil {
+		t.Fatal("expected an error once the queue is empty")
+	}
+}
+
+func TestQueueInputMultipleValues(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 INPUT A, B$`)
+	bob.QueueInput(`7, "X, Y"`)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if a := bob.Variables["A"]; a.Int != 7 {
+		t.Errorf("A = %v, want 7", a)
+	}
+	if b := bob.Variables["B$"].Str; b != "X, Y" {
+		t.Errorf("B$ = %q, want %q", b, "X, Y")
+	}
+}
+
+func TestInputMultipleValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{
+			name:   "exact",
+			input:  "1, 2.5, HELLO\n",
+			output: "? 1 2.5 HELLO\n",
+		},
+		{
+			name:   "short line prompts for the rest",
+			input:  "1\n2.5, HELLO\n",
+			output: "? ?? 1 2.5 HELLO\n",
+		},
+		{
+			name:   "one value a line",
+			input:  "1\n2.5\nHELLO\n",
+			output: "? ?? ?? 1 2.5 HELLO\n",
+		},
+		{
+			name:   "long line",
+			input:  "1, 2.5, HELLO, EXTRA\n",
+			output: "? ?Extra ignored\n1 2.5 HELLO\n",
+		},
+		{
+			name:   "quoted comma",
+			input:  `1, 2.5, "HELLO, WORLD"` + "\n",
+			output: "? 1 2.5 HELLO, WORLD\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t,
+				`10 INPUT A, B, C$`,
+				`20 PRINT A; " "; B; " "; C$`,
+			)
+			bob.Input = strings.NewReader(tc.input)
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := out.String(); got != tc.output {
+				t.Errorf("output = %q, want %q", got, tc.output)
+			}
+			if b := bob.Variables["B"]; !b.IsFloat {
+				t.Errorf("B = %#v, want a float", b)
+			}
+		})
+	}
+}
+
+func TestInputTypeMismatch(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 INPUT A, B`)
+	bob.Input = strings.NewReader("1, TWO\n")
+	if err := bob.Run(); err == nil {
+		t.Error("expected an error reading TWO into B")
+	}
+}
+
+func TestInputStr(t *testing.T) {
+	data := []byte("HELLO\nWORLD")
+	bob, out := newTestInterpreter(t,
+		`10 OPEN "I", #1, "F.TXT"`,
+		`20 LET A$=INPUT$(3, #1)`,
+		`30 LET B$=INPUT$(5, #1)`,
+		`40 LET C$=INPUT$(2)`,
+		`50 PRINT "["; A$; "]["; B$; "]["; C$; "]"`,
+	)
+	bob.Files = memFS{"F.TXT": &data}
+	bob.Input = strings.NewReader("KEYS")
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// the newline is read like any other character
+	if got, want := out.String(), "[HEL][LO\nWO][KE]\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestInputStrQueued(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 PRINT "["; INPUT$(2); "]["; INPUT$(3); "]["; INPUT$(1); "]"`,
+	)
+	bob.QueueInput("ABC", "D")
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// each queued value is a line, ending in a newline
+	if got, want := out.String(), "[AB][C\nD][\n]\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestInputStrErrors(t *testing.T) {
+	data := []byte("ABC")
+	for _, program := range [][]string{
+		{`10 OPEN "I", #1, "F.TXT"`, `20 PRINT INPUT$(4, #1)`},
+		{`10 OPEN "I", #1, "F.TXT"`, `20 PRINT INPUT$(3, #1)`, `30 PRINT INPUT$(1, #1)`},
+		{`10 PRINT INPUT$(1)`},
+		{`10 PRINT INPUT$(1, #2)`},
+		{`10 PRINT INPUT$(-1)`},
+		{`10 PRINT INPUT$(256)`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		bob.Files = memFS{"F.TXT": &data}
+		if err := bob.Run(); err == nil {
+			t.Errorf("%q: expected an error", program)
+		}
+	}
+}
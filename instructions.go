@@ -0,0 +1,410 @@
+package gdbasic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instructioner is a single executable BASIC statement.
+type Instructioner interface {
+	fmt.Stringer
+	Execute(*Interpreter) error
+}
+
+// LineReferencer is implemented by instructions that refer to other lines,
+// such as GOTO, so the references can be found when editing the program.
+type LineReferencer interface {
+	LineRefs() []int
+	// RenumberRefs returns a copy of the instruction with its references
+	// replaced using the old to new line number mapping.
+	RenumberRefs(lines map[int]int) Instructioner
+}
+
+// RemInstruction is a comment, it does nothing when run.
+type RemInstruction struct {
+	Text string
+}
+
+func (RemInstruction) Execute(*Interpreter) error { return nil }
+
+func (ri RemInstruction) String() string {
+	if ri.Text == "" {
+		return "REM"
+	}
+	return "REM " + ri.Text
+}
+
+// TronInstruction turns on tracing, writing the number of each line executed
+// to the interpreter's TraceOutput.
+type TronInstruction struct{}
+
+func (TronInstruction) Execute(intp *Interpreter) error {
+	intp.trace = true
+	return nil
+}
+
+func (TronInstruction) String() string { return "TRON" }
+
+// TroffInstruction turns off tracing.
+type TroffInstruction struct{}
+
+func (TroffInstruction) Execute(intp *Interpreter) error {
+	intp.trace = false
+	return nil
+}
+
+func (TroffInstruction) String() string { return "TROFF" }
+
+// ClearInstruction throws away all the variables, arrays and constants and
+// moves READ back to the first DATA. Unlike NEW the program is kept.
+type ClearInstruction struct{}
+
+func (ClearInstruction) Execute(intp *Interpreter) error {
+	intp.lockVars()
+	intp.Variables = map[string]Value{}
+	intp.unlockVars()
+	intp.Arrays = nil
+	intp.constants = nil
+	intp.varAddrs = nil
+	intp.stringBlocks, intp.stringTop = nil, 0
+	intp.data = dataPointer{}
+	return nil
+}
+
+func (ClearInstruction) String() string { return "CLEAR" }
+
+// StopInstruction halts the program with ErrStop, it can be carried on
+// with Continue.
+type StopInstruction struct{}
+
+func (StopInstruction) Execute(intp *Interpreter) error {
+	line, err := intp.executingLine()
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w in %d", ErrStop, line)
+}
+
+func (StopInstruction) String() string { return "STOP" }
+
+// SleepInstruction pauses the program for a number of seconds, or for WAIT
+// milliseconds, using the interpreter's Sleep.
+type SleepInstruction struct {
+	Duration Expression
+	// Millis is set for WAIT, which takes milliseconds.
+	Millis bool
+}
+
+func (si SleepInstruction) Execute(intp *Interpreter) error {
+	val, err := si.Duration.Eval(intp)
+	if err != nil {
+		return err
+	}
+	if val.IsStr {
+		return fmt.Errorf("type mismatch: %s", si)
+	}
+	unit := time.Second
+	if si.Millis {
+		unit = time.Millisecond
+	}
+	if d := time.Duration(val.float() * float64(unit)); d > 0 {
+		intp.Sleep(d)
+	}
+	return nil
+}
+
+func (si SleepInstruction) String() string {
+	if si.Millis {
+		return fmt.Sprintf("WAIT %s", si.Duration)
+	}
+	return fmt.Sprintf("SLEEP %s", si.Duration)
+}
+
+func NewSleepInstruction(_ int, remainder string, millis bool) (*SleepInstruction, error) {
+	// SLEEP 2 or WAIT 500
+	expr, err := ParseExpression(remainder)
+	if err != nil {
+		return nil, err
+	}
+	return &SleepInstruction{Duration: expr, Millis: millis}, nil
+}
+
+// LetInstruction assigns the result of an expression to a variable, or to an
+// element of an array when there are Subscripts.
+type LetInstruction struct {
+	VarName    string
+	Subscripts []Expression
+	Expr       Expression
+}
+
+func (li LetInstruction) Execute(intp *Interpreter) error {
+	val, err := li.Expr.Eval(intp)
+	if err != nil {
+		return err
+	}
+	if li.Subscripts == nil {
+		if err := intp.checkWritable(li.VarName); err != nil {
+			return err
+		}
+		return intp.setVariable(li.VarName, val)
+	}
+	subs := make([]Value, len(li.Subscripts))
+	for i, sub := range li.Subscripts {
+		if subs[i], err = sub.Eval(intp); err != nil {
+			return err
+		}
+	}
+	return intp.setArrayElement(li.VarName, subs, val)
+}
+
+func (li LetInstruction) String() string {
+	if li.Subscripts != nil {
+		return fmt.Sprintf("LET %s=%s", FuncExpression{Name: li.VarName, Args: li.Subscripts}, li.Expr)
+	}
+	return fmt.Sprintf("LET %s=%s", li.VarName, li.Expr)
+}
+
+func NewLetInstruction(_ int, remainder string) (*LetInstruction, error) {
+	// LET A=1000
+	idx := strings.Index(remainder, "=")
+	if idx == -1 {
+		return nil, parseErrorf(len(remainder), "invalid let statment, missing `=`")
+	}
+	name := strings.TrimSpace(remainder[:idx])
+	expr, err := ParseExpression(remainder[idx+1:])
+	if err != nil {
+		return nil, shiftParseError(err, idx+1)
+	}
+	li := &LetInstruction{
+		VarName: varName(name),
+		Expr:    expr,
+	}
+	if strings.Contains(name, "(") {
+		// LET A(I)=1
+		target, err := ParseExpression(name)
+		if err != nil {
+			return nil, err
+		}
+		elem, ok := target.(FuncExpression)
+		if !ok || len(elem.Args) == 0 {
+			return nil, fmt.Errorf("invalid let target `%s`", name)
+		}
+		li.VarName, li.Subscripts = elem.Name, elem.Args
+	}
+	return li, nil
+}
+
+// ConstInstruction sets a variable that can not be assigned to afterwards,
+// by LET or anything else that sets variables.
+type ConstInstruction struct {
+	VarName string
+	Expr    Expression
+}
+
+func (ci ConstInstruction) Execute(intp *Interpreter) error {
+	if intp.constants[ci.VarName] {
+		return fmt.Errorf("constant %s already defined", ci.VarName)
+	}
+	val, err := ci.Expr.Eval(intp)
+	if err != nil {
+		return err
+	}
+	if val.IsStr != isStringVar(ci.VarName) {
+		return fmt.Errorf("type mismatch: %s", ci)
+	}
+	if err := intp.setVariable(ci.VarName, val); err != nil {
+		return err
+	}
+	if intp.constants == nil {
+		intp.constants = map[string]bool{}
+	}
+	intp.constants[ci.VarName] = true
+	return nil
+}
+
+func (ci ConstInstruction) String() string {
+	return fmt.Sprintf("CONST %s=%s", ci.VarName, ci.Expr)
+}
+
+func NewConstInstruction(_ int, remainder string) (*ConstInstruction, error) {
+	// CONST PI=3.14159
+	idx := strings.Index(remainder, "=")
+	if idx == -1 {
+		return nil, parseErrorf(len(remainder), "invalid const statement, missing `=`")
+	}
+	expr, err := ParseExpression(remainder[idx+1:])
+	if err != nil {
+		return nil, shiftParseError(err, idx+1)
+	}
+	ci := &ConstInstruction{VarName: varName(remainder[:idx]), Expr: expr}
+	if ci.VarName == "" || strings.ContainsAny(ci.VarName, "() ") {
+		return nil, fmt.Errorf("const has a bad name `%s`", ci.VarName)
+	}
+	return ci, nil
+}
+
+// MidInstruction overwrites part of a string variable in place, as in
+// LET MID$(A$, 2, 3)="XYZ". The length of the variable never changes, no
+// more than Length characters, or the rest of the string when there is no
+// Length, are replaced.
+type MidInstruction struct {
+	VarName string
+	Start   Expression
+	Length  Expression
+	Expr    Expression
+}
+
+func (mi MidInstruction) Execute(intp *Interpreter) error {
+	val, err := mi.Expr.Eval(intp)
+	if err != nil {
+		return err
+	}
+	if !val.IsStr {
+		return fmt.Errorf("MID$: type mismatch")
+	}
+	if err := intp.checkWritable(mi.VarName); err != nil {
+		return err
+	}
+	cur, err := intp.variable(mi.VarName)
+	if err != nil {
+		return err
+	}
+	start, err := evalInt(intp, mi.Start)
+	if err != nil {
+		return err
+	}
+	if start < 1 || start > len(cur.Str) {
+		return fmt.Errorf("MID$: illegal start %d", start)
+	}
+	n := len(cur.Str) - start + 1
+	if mi.Length != nil {
+		length, err := evalInt(intp, mi.Length)
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			return fmt.Errorf("MID$: illegal length %d", length)
+		}
+		if length < n {
+			n = length
+		}
+	}
+	if len(val.Str) < n {
+		n = len(val.Str)
+	}
+	s := cur.Str[:start-1] + val.Str[:n] + cur.Str[start-1+n:]
+	return intp.setVariable(mi.VarName, strValue(s))
+}
+
+func (mi MidInstruction) String() string {
+	args := []Expression{Reference(mi.VarName), mi.Start}
+	if mi.Length != nil {
+		args = append(args, mi.Length)
+	}
+	return fmt.Sprintf("LET %s=%s", FuncExpression{Name: "MID$", Args: args}, mi.Expr)
+}
+
+func NewMidInstruction(_ int, remainder string) (*MidInstruction, error) {
+	// LET MID$(A$, 2, 3)="XYZ"
+	idx := strings.Index(remainder, "=")
+	if idx == -1 {
+		return nil, parseErrorf(len(remainder), "invalid let statment, missing `=`")
+	}
+	expr, err := ParseExpression(remainder[idx+1:])
+	if err != nil {
+		return nil, shiftParseError(err, idx+1)
+	}
+	target, err := ParseExpression(remainder[:idx])
+	if err != nil {
+		return nil, err
+	}
+	fe, ok := target.(FuncExpression)
+	if !ok || len(fe.Args) < 2 || len(fe.Args) > 3 {
+		return nil, fmt.Errorf("invalid MID$ target `%s`", strings.TrimSpace(remainder[:idx]))
+	}
+	ref, ok := fe.Args[0].(Reference)
+	if !ok || !isStringVar(string(ref)) {
+		return nil, fmt.Errorf("MID$ needs a string variable not `%s`", fe.Args[0])
+	}
+	mi := &MidInstruction{VarName: string(ref), Start: fe.Args[1], Expr: expr}
+	if len(fe.Args) == 3 {
+		mi.Length = fe.Args[2]
+	}
+	return mi, nil
+}
+
+// SwapInstruction exchanges the values of two variables. A variable that has
+// not been set yet is created with the zero value for its type, "" for a
+// string and 0 for a number, unless StrictVars is set when it is an error.
+// Both variables must be strings, going by the $ suffix, or both be numbers.
+type SwapInstruction struct {
+	A, B string
+}
+
+func (si SwapInstruction) Execute(intp *Interpreter) error {
+	for _, name := range []string{si.A, si.B} {
+		if err := intp.checkWritable(name); err != nil {
+			return err
+		}
+	}
+	a, err := intp.variable(si.A)
+	if err != nil {
+		return err
+	}
+	b, err := intp.variable(si.B)
+	if err != nil {
+		return err
+	}
+	if err := intp.setVariable(si.A, b); err != nil {
+		return err
+	}
+	return intp.setVariable(si.B, a)
+}
+
+func (si SwapInstruction) String() string {
+	return fmt.Sprintf("SWAP %s, %s", si.A, si.B)
+}
+
+func NewSwapInstruction(_ int, remainder string) (*SwapInstruction, error) {
+	// SWAP A, B
+	a, b, ok := strings.Cut(remainder, ",")
+	if !ok {
+		return nil, fmt.Errorf("swap needs two variables `%s`", remainder)
+	}
+	si := &SwapInstruction{A: varName(a), B: varName(b)}
+	if si.A == "" || si.B == "" {
+		return nil, fmt.Errorf("swap needs two variables `%s`", remainder)
+	}
+	if isStringVar(si.A) != isStringVar(si.B) {
+		return nil, fmt.Errorf("type mismatch swapping %s and %s", si.A, si.B)
+	}
+	return si, nil
+}
+
+// JumpInstruction is a GOTO to the line number.
+type JumpInstruction int
+
+func (jmp JumpInstruction) Execute(intp *Interpreter) error {
+	return intp.SetPC(int(jmp))
+}
+
+func (jmp JumpInstruction) String() string {
+	return fmt.Sprintf("GOTO %v", int(jmp))
+}
+
+func (jmp JumpInstruction) LineRefs() []int { return []int{int(jmp)} }
+
+func (jmp JumpInstruction) RenumberRefs(lines map[int]int) Instructioner {
+	return JumpInstruction(lines[int(jmp)])
+}
+
+func NewJumpInstruction(_ int, remainder string) (JumpInstruction, error) {
+
+	i64, err := strconv.ParseInt(remainder, 10, 32)
+	if err != nil {
+		return JumpInstruction(0), fmt.Errorf("goto has a bad line number `%s`: %v", remainder, err)
+	}
+	return JumpInstruction(i64), nil
+}
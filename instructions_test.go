@@ -0,0 +1,364 @@
+package gdbasic
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSwap(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{
+			name:    "numbers",
+			program: []string{`10 LET A=1`, `20 LET B=2.5`, `30 SWAP A, B`, `40 PRINT A; " "; B`},
+			want:    "2.5 1\n",
+		},
+		{
+			name:    "strings",
+			program: []string{`10 LET A$="X"`, `20 LET B$="Y"`, `30 SWAP A$,B$`, `40 PRINT A$; B$`},
+			want:    "YX\n",
+		},
+		{
+			name:    "unset variables are zero",
+			program: []string{`10 LET A=7`, `20 SWAP A, B`, `30 PRINT A; " "; B`},
+			want:    "0 7\n",
+		},
+		{
+			name:    "twice is unchanged",
+			program: []string{`10 LET A=1`, `20 LET B=2`, `30 SWAP A, B`, `40 SWAP B, A`, `50 PRINT A; " "; B`},
+			want:    "1 2\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runProgram(t, tc.program...); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSwapErrors(t *testing.T) {
+	for _, line := range []string{`SWAP A, B$`, `SWAP A$, B`, `SWAP A`, `SWAP A,`, `SWAP , B`} {
+		if _, err := parseInstruction(10, line); err == nil {
+			t.Errorf("%s: expected an error", line)
+		}
+	}
+
+	bob, _ := newTestInterpreter(t, `10 LET A=1`, `20 SWAP A, B`)
+	bob.StrictVars = true
+	if err := bob.Run(); err == nil {
+		t.Error("SWAP with an unset variable and StrictVars: expected an error")
+	}
+}
+
+func TestSleep(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    []time.Duration
+	}{
+		{"seconds", []string{`10 SLEEP 2`}, []time.Duration{2 * time.Second}},
+		{"fraction of a second", []string{`10 SLEEP 0.25`}, []time.Duration{250 * time.Millisecond}},
+		{"milliseconds", []string{`10 WAIT 500`}, []time.Duration{500 * time.Millisecond}},
+		{"expression", []string{`10 LET D=3`, `20 SLEEP D*2`, `30 WAIT D+1`}, []time.Duration{6 * time.Second, 4 * time.Millisecond}},
+		{"zero or less does not sleep", []string{`10 SLEEP 0`, `20 WAIT -5`}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tc.program...)
+			var slept []time.Duration
+			bob.Sleep = func(d time.Duration) { slept = append(slept, d) }
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if !reflect.DeepEqual(slept, tc.want) {
+				t.Errorf("slept %v, want %v", slept, tc.want)
+			}
+		})
+	}
+}
+
+func TestSleepErrors(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 SLEEP "A"`)
+	bob.Sleep = func(time.Duration) { t.Error("slept") }
+	if err := bob.Run(); err == nil {
+		t.Error("expected a type mismatch")
+	}
+	if _, err := parseInstruction(10, `WAIT (`); err == nil {
+		t.Error("WAIT (: expected an error")
+	}
+}
+
+func TestMidAssign(t *testing.T) {
+	tests := []struct {
+		name string
+		stmt string
+		want string
+	}{
+		{"exact fit", `MID$(A$, 2, 3)="XYZ"`, "AXYZEF"},
+		{"shorter", `MID$(A$, 2, 3)="X"`, "AXCDEF"},
+		{"longer", `MID$(A$, 2, 3)="XYZW"`, "AXYZEF"},
+		{"no length", `MID$(A$, 5)="XYZ"`, "ABCDXY"},
+		{"at the end", `MID$(A$, 6, 1)="Z"`, "ABCDEZ"},
+		{"zero length", `MID$(A$, 1, 0)="Z"`, "ABCDEF"},
+		{"with LET", `LET MID$(A$, 1)="Z"`, "ZBCDEF"},
+		{"expressions", `MID$(A$, 1+1, 2*1)="X"+"Y"`, "AXYDEF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runProgram(t,
+				`10 LET A$="ABCDEF"`,
+				`20 `+tt.stmt,
+				`30 PRINT A$`,
+			)
+			// the length of A$ never changes
+			if want := tt.want + "\n"; got != want {
+				t.Errorf("printed %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMidAssignErrors(t *testing.T) {
+	for _, stmt := range []string{
+		`MID$(A$, 0)="X"`,
+		`MID$(A$, 7)="X"`,
+		`MID$(A$, 1, -1)="X"`,
+		`MID$(A$, 1)=5`,
+	} {
+		bob, _ := newTestInterpreter(t, `10 LET A$="ABCDEF"`, `20 `+stmt)
+		if err := bob.Run(); err == nil {
+			t.Errorf("%s: expected an error", stmt)
+		}
+	}
+	for _, stmt := range []string{
+		`MID$(A, 1)="X"`,
+		`MID$(A$)="X"`,
+		`MID$(A$, 1)`,
+	} {
+		if err := NewInterpreter().Interpret(`10 ` + stmt); err == nil {
+			t.Errorf("%s: expected a parse error", stmt)
+		}
+	}
+}
+
+func TestConst(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 CONST PI=3.14159`,
+		`20 CONST N$="BOB"`,
+		`30 CONST TWO=1+1`,
+		`40 PRINT PI; " "; N$; " "; TWO*PI`,
+	)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := "3.14159 BOB 6.28318\n"; out.String() != want {
+		t.Errorf("printed %q, want %q", out.String(), want)
+	}
+	// running again defines them again
+	out.Reset()
+	if err := bob.Run(); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if want := "3.14159 BOB 6.28318\n"; out.String() != want {
+		t.Errorf("second run printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestConstAssign(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		stmt string
+	}{
+		{"LET", `LET C=2`},
+		{"implicit LET", `C=2`},
+		{"CONST", `CONST C=2`},
+		{"SWAP", `SWAP C, D`},
+		{"READ", `READ C`},
+		{"INPUT", `INPUT C`},
+		{"FOR", `FOR C=1 TO 2`},
+		{"MID$", `MID$(C$, 1)="X"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t,
+				`10 CONST C=1`,
+				`20 CONST C$="A"`,
+				`30 DATA 5`,
+				`40 `+tt.stmt,
+				`50 PRINT "NOT REACHED"`,
+			)
+			bob.Input = strings.NewReader("5\n")
+			err := bob.Run()
+			if err == nil || !strings.Contains(err.Error(), "constant") {
+				t.Errorf("Run: got %v, want a constant error", err)
+			}
+			if strings.Contains(out.String(), "NOT REACHED") {
+				t.Error("the program carried on after the error")
+			}
+			if val, _ := bob.GetVar("C"); val.Int != 1 {
+				t.Errorf("C = %v, want 1", val)
+			}
+		})
+	}
+}
+
+func TestConstErrors(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 CONST A$=1`)
+	if err := bob.Run(); err == nil {
+		t.Error("CONST A$=1: expected a type mismatch")
+	}
+	for _, line := range []string{`10 CONST A`, `10 CONST A(1)=1`, `10 CONST A B=1`} {
+		if err := NewInterpreter().Interpret(line); err == nil {
+			t.Errorf("%s: expected a parse error", line)
+		}
+	}
+	bob, _ = newTestInterpreter(t, `10 CONST PI=3.14`)
+	if got, want := listing(t, bob), "10 CONST PI=3.14\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+}
+
+func TestClear(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 DATA 1, 2`,
+		`20 LET A=1`,
+		`30 LET B$="X"`,
+		`40 DIM C(3)`,
+		`50 CONST D=4`,
+		`60 READ E`,
+		`70 CLEAR`,
+		`80 READ F`,
+		`90 PRINT F`,
+	)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// READ starts again from the first DATA
+	if out.String() != "1\n" {
+		t.Errorf("printed %q, want %q", out.String(), "1\n")
+	}
+	for name := range bob.Variables {
+		if name != "F" {
+			t.Errorf("variable %s survived CLEAR", name)
+		}
+	}
+	if len(bob.Arrays) != 0 {
+		t.Errorf("arrays survived CLEAR: %v", bob.Arrays)
+	}
+	if len(bob.Instructions) != 9 {
+		t.Errorf("CLEAR left %d instructions, want 9", len(bob.Instructions))
+	}
+}
+
+func TestClearDirect(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 LET A=1`, `20 DIM B(2)`, `30 CONST C=3`)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := bob.Execute(`CLEAR`); err != nil {
+		t.Fatalf("CLEAR: %v", err)
+	}
+	if len(bob.Variables) != 0 || len(bob.Arrays) != 0 {
+		t.Errorf("CLEAR left %v and %v", bob.Variables, bob.Arrays)
+	}
+	// the constant can be set again and the program is still there
+	if err := bob.Execute(`LET C=5`); err != nil {
+		t.Errorf("LET C=5 after CLEAR: %v", err)
+	}
+	if got, want := listing(t, bob), "10 LET A=1\n20 DIM B(2)\n30 CONST C=3\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+	if err := bob.Execute(`DIM B(4)`); err != nil {
+		t.Errorf("DIM B(4) after CLEAR: %v", err)
+	}
+}
+
+func TestImplicitLet(t *testing.T) {
+	got := runProgram(t,
+		`10 X = 10`,
+		`20 X$ = "hi"`,
+		`30 Y%=X*2`,
+		`40 A(2) = 7`,
+		`50 IF X=10 THEN PRINT "TEN"`,
+		`60 IF X = 11 THEN Z=1 ELSE Z=2`,
+		`70 PRINT X; X$; Y%; A(2); Z`,
+	)
+	if want := "TEN\n10hi2072\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestIsAssignment(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{`X = 10`, true},
+		{`X=10`, true},
+		{`X$ = "hi"`, true},
+		{`X% =1`, true},
+		{`A(1, 2)=3`, true},
+		{`A(B(1))=3`, true},
+		{`AB1=3`, true},
+		{`IF A=1 THEN 20`, false},
+		{`PRINT A=1`, false},
+		{`FOR I=1 TO 3`, false},
+		{`LET A=1`, false},
+		{`A(1=3`, false},
+		{`A`, false},
+		{`1=2`, false},
+		{`=2`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := isAssignment(tt.stmt); got != tt.want {
+			t.Errorf("isAssignment(%q) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestImplicitLetParse(t *testing.T) {
+	// listed with LET, and IF keeps its condition
+	bob, _ := newTestInterpreter(t, `10 X = 10`, `20 IF A=1 THEN 10`, `30 A(1)=2`)
+	if got, want := listing(t, bob), "10 LET X=10\n20 IF A=1 THEN 10\n30 LET A(1)=2\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+	if _, ok := bob.Instructions[20].(*IfInstruction); !ok {
+		t.Errorf("line 20 is a %T, want an IF", bob.Instructions[20])
+	}
+	// parse error columns are in the line as written, without the LET
+	err := NewInterpreter().Interpret(`10 X=(1`)
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Col != 7 {
+		t.Errorf("Interpret(`10 X=(1`) = %v, want a ParseError at col 7", err)
+	}
+}
+
+func TestConstReadKeepsData(t *testing.T) {
+	// a READ into a constant reads nothing, so the DATA after it is still
+	// read in order
+	for _, stmt := range []string{`READ C`, `READ A, C`} {
+		got := runProgram(t,
+			`10 DATA 1, 2, 3`,
+			`20 CONST C=9`,
+			`30 ON ERROR GOTO 100`,
+			`40 `+stmt,
+			`50 READ X, Y`,
+			`60 PRINT X; Y; C`,
+			`70 GOTO 200`,
+			`100 PRINT "ERR"`,
+			`110 RESUME NEXT`,
+			`200 REM`,
+		)
+		if want := "ERR\n129\n"; got != want {
+			t.Errorf("%s: printed %q, want %q", stmt, got, want)
+		}
+	}
+}
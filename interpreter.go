@@ -0,0 +1,983 @@
+// Package gdbasic is a small BASIC interpreter.
+//
+// Programs are loaded a line at a time with Interpret and executed with Run:
+//
+//	bob := gdbasic.NewInterpreter()
+//	bob.Interpret(`10 PRINT "HELLO"`)
+//	bob.Run()
+package gdbasic
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interpreter holds a BASIC program and the state needed to run it.
+type Interpreter struct {
+	Variables map[string]Value
+	// Arrays are the arrays, dimensioned by DIM or on first use.
+	Arrays       map[string]*Array
+	Instructions map[int]Instructioner
+	// Functions are the functions defined by DEF FN.
+	Functions map[string]UserFunction
+
+	// Output is where PRINT writes to, defaults to os.Stdout.
+	Output io.Writer
+	// PrinterOutput is where LPRINT writes to, defaults to io.Discard.
+	PrinterOutput io.Writer
+	// TraceOutput is where TRON writes the line numbers it traces, defaults
+	// to os.Stderr.
+	TraceOutput io.Writer
+	// Input is where INPUT reads from, defaults to os.Stdin.
+	Input io.Reader
+	// Files is used by OPEN to open files, defaults to the OSFileSystem.
+	Files FileSystem
+	// InputPromptSuffix is printed after the INPUT prompt, defaults to "? ".
+	InputPromptSuffix string
+	// Terminal is used by INPUT NOECHO to turn off echoing, if it is nil the
+	// input is read without changing the echo.
+	Terminal Terminal
+
+	// TabNewline selects the GW-BASIC behaviour for TAB(n) when the cursor is
+	// already past column n: a newline is printed and then the padding to
+	// column n. By default TAB does nothing in that case.
+	TabNewline bool
+
+	// IntSize is the number of bits in an integer, 16 or 32, integer
+	// arithmetic that does not fit is an overflow error. Defaults to 32.
+	IntSize int
+
+	// DivZeroZero makes `/`, `\` and MOD by zero return 0 instead of
+	// erroring, as some lenient dialects do.
+	DivZeroZero bool
+
+	// NoEscapes stops COLOR and CLS writing ANSI escape sequences to
+	// Output, for when it is not a terminal.
+	NoEscapes bool
+
+	// Sleep is used by SLEEP and WAIT to pause, defaults to time.Sleep.
+	Sleep func(time.Duration)
+	// Now is the clock read by TIMER and TIME$, defaults to time.Now.
+	Now func() time.Time
+
+	// Frozen stops the program being changed, Interpret, Renumber and
+	// Insert return ErrFrozen and Delete removes nothing.
+	Frozen bool
+
+	// KeepSource keeps the text of each line given to Interpret so List
+	// can show it exactly as it was typed.
+	KeepSource bool
+
+	// ThreadSafe guards Variables with a mutex so that GetVar and SetVar
+	// can be called from other goroutines while the program runs. It must
+	// be set before the program is run.
+	ThreadSafe bool
+
+	// StrictVars makes using a variable before it has been set an error.
+	// By default, as in BASIC, it is 0, or "" for a string variable.
+	StrictVars bool
+
+	// TrackCoverage counts how many times each line is executed, see
+	// Coverage.
+	TrackCoverage bool
+
+	// MaxCallDepth is how deeply GOSUBs, and calls of DEF FN functions, may
+	// be nested before it is an out of memory error, defaults to 1000. 0 is
+	// no limit.
+	MaxCallDepth int
+
+	// MaxOutputBytes is how many bytes a program may write to Output before
+	// it is stopped with ErrOutputLimit. 0 is no limit.
+	MaxOutputBytes int
+
+	// StringMemory keeps the text of string variables in the simulated
+	// memory, as GW-BASIC does, so that PEEK can read it. The 3 bytes at
+	// VARPTR of a string variable are its length and then the address of
+	// its text, low byte first. Elements of string arrays are not stored.
+	StringMemory bool
+
+	// AngleMode is whether SIN, COS, TAN and ATN work in radians, the
+	// default, or degrees. It is set by the RAD and DEG statements.
+	AngleMode AngleMode
+
+	intructionIndex []int
+	pc              int
+	screen          cursor
+	printer         cursor
+	breakpoints     map[int]bool
+	atBreakpoint    bool
+	trace           bool
+	input           *bufio.Reader
+	inputSource     io.Reader
+	batch           bool
+	inputQueue      []string
+	channels        map[int]*channel
+	varAddrs        map[string]int
+	hostFuncs       map[string]HostFuncEx
+	usr             [10]Value
+	nextVarAddr     int
+	memory          []byte
+	// stringBlocks is where in memory the text of each string variable is
+	// kept for StringMemory, string space grows down from stringTop.
+	stringBlocks map[string]memBlock
+	stringTop    int
+	base         int
+	zoneWidth    int
+	// errHandler is the line set by ON ERROR GOTO, errCode and errLine are
+	// the last error it trapped.
+	errHandler   int
+	errCode      int
+	errLine      int
+	inErrHandler bool
+	// constants are the variables made by CONST, that can not be assigned.
+	constants map[string]bool
+	covered   map[int]int
+	// labels maps the labels of lines to their line numbers, it is built
+	// along with the instruction index.
+	labels map[string]int
+	// outputBytes counts what has been written to Output for MaxOutputBytes.
+	outputBytes  int
+	rng          *rand.Rand
+	rngSrc       *countingSource
+	lastRnd      float64
+	fixedRnd     []int
+	nextFixedRnd int
+	returnStack  []int
+	loopStack    []loopFrame
+	data         dataPointer
+	source       map[int]string
+	direct       bool
+	canContinue  bool
+	varsMu       sync.Mutex
+
+	// fnDepth is how many DEF FN calls are being evaluated.
+	fnDepth int
+}
+
+var (
+	// ErrBreakpoint is returned by Run when it stops at a breakpoint.
+	ErrBreakpoint = errors.New("breakpoint hit")
+	// ErrExecutionLimit is returned by RunWithLimit when the program has
+	// executed too many instructions.
+	ErrExecutionLimit = errors.New("execution limit exceeded")
+	// ErrStop is returned by Run when the program executes STOP.
+	ErrStop = errors.New("stopped")
+	// ErrFrozen is returned when changing a program that is Frozen.
+	ErrFrozen = errors.New("program is frozen")
+	// ErrOutputLimit is returned by Run when the program has written more
+	// than MaxOutputBytes.
+	ErrOutputLimit = errors.New("output limit exceeded")
+)
+
+func getCommandIdx(s string) (string, int) {
+	idx := strings.IndexAny(s, ` "`)
+	if idx == -1 {
+		return s, idx
+	}
+	return s[:idx], idx
+}
+
+// Interpret parses a numbered line of BASIC and adds it to the program,
+// replacing any instruction already at that line number. A line number on
+// its own deletes that line, and a blank line is ignored.
+func (bob *Interpreter) Interpret(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	raw := line
+	if ln, err := strconv.ParseInt(strings.TrimSpace(line), 10, 32); err == nil {
+		if bob.Frozen {
+			return fmt.Errorf("line %v: %w", ln, ErrFrozen)
+		}
+		bob.Delete(int(ln), int(ln))
+		return nil
+	}
+	// remove the line number
+	idx := strings.Index(line, " ")
+	if idx == -1 {
+		return fmt.Errorf("DID NOT FIND A LINE NUMBER")
+	}
+	lineString := line[:idx]
+	i64, err := strconv.ParseInt(lineString, 10, 32)
+	if err != nil {
+		return fmt.Errorf("bad line number `%s`: %v", lineString, err)
+
+	}
+	lineNumber := int(i64)
+	line = line[idx+1:]
+
+	label, stmt, hasLabel := cutLabel(line)
+	if hasLabel {
+		// the columns of errors are still from the start of the line
+		idx += len(line) - len(strings.TrimLeft(stmt, " "))
+		line = strings.TrimLeft(stmt, " ")
+	}
+	var instruction Instructioner
+	if !hasLabel || line != "" {
+		instruction, err = parseInstruction(lineNumber, line)
+	}
+	if hasLabel {
+		instruction = LabeledInstruction{Label: label, Instructioner: instruction}
+	}
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.Line = lineNumber
+		pe.Col += idx + 1
+		pe.Source = raw
+	}
+	if err != nil {
+		return err
+	}
+	if bob.Frozen {
+		return fmt.Errorf("line %v: %w", lineNumber, ErrFrozen)
+	}
+	bob.Instructions[lineNumber] = instruction
+	if bob.KeepSource {
+		if bob.source == nil {
+			bob.source = map[int]string{}
+		}
+		bob.source[lineNumber] = raw
+	} else {
+		delete(bob.source, lineNumber)
+	}
+	bob.invalidate()
+	return nil
+}
+
+// Execute runs a line in direct mode. A line that starts with a line number
+// is added to the program as Interpret does, anything else is run straight
+// away against the current state without being stored, so `PRINT 2+3` prints
+// 5 and `LET A=1` sets A for the program. A GOTO moves to the line so the
+// next Run continues from there.
+func (bob *Interpreter) Execute(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	if isDigit(line[0]) {
+		return bob.Interpret(line)
+	}
+	if line == "CONT" {
+		return bob.Continue()
+	}
+	instruction, err := parseInstruction(-1, line)
+	if err != nil {
+		return err
+	}
+	if err = bob.buildInstructionIndex(); err != nil {
+		return err
+	}
+	bob.direct = true
+	defer func() { bob.direct = false }()
+	return instruction.Execute(bob)
+}
+
+// ParseError is an error in the text of a line, Col is the byte offset in
+// the line where the problem was found.
+type ParseError struct {
+	Line int
+	Col  int
+	Msg  string
+	// Source is the line being parsed, when it is known the error shows
+	// it with a caret under the column.
+	Source string
+}
+
+func (pe *ParseError) Error() string {
+	msg := fmt.Sprintf("line %d col %d: %s", pe.Line, pe.Col, pe.Msg)
+	if pe.Source == "" || pe.Col > len(pe.Source) {
+		return msg
+	}
+	return msg + "\n" + pe.Source + "\n" + strings.Repeat(" ", pe.Col) + "^"
+}
+
+// shiftParseError moves the column of err, if it is a ParseError, by n. It
+// is used when the text that was parsed started n bytes into the remainder.
+func shiftParseError(err error, n int) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.Col += n
+	}
+	return err
+}
+
+// parseErrorf returns a ParseError at col, the line is filled in by
+// Interpret.
+func parseErrorf(col int, format string, a ...interface{}) error {
+	return &ParseError{Col: col, Msg: fmt.Sprintf(format, a...)}
+}
+
+// parseInstruction parses a single statement, line is the number of the line
+// it is on. An assignment without LET, such as A=5, is parsed as a LET. The
+// column of a ParseError is moved from the statement's remainder to the
+// statement.
+func parseInstruction(lineNumber int, line string) (Instructioner, error) {
+	implicit := 0
+	if isAssignment(line) {
+		implicit = len("LET ")
+		line = "LET " + line
+	}
+	instruction, err := parseStatement(lineNumber, line)
+	return instruction, shiftParseError(err, remainderOffset(line)-implicit)
+}
+
+// isAssignment reports whether stmt starts with a variable, or an array
+// element, followed by =, making it an assignment without the LET keyword.
+// Statements that start with a command, such as IF A=1 THEN, are not.
+func isAssignment(stmt string) bool {
+	if len(stmt) == 0 || !isIdentStart(stmt[0]) {
+		return false
+	}
+	i := 1
+	for i < len(stmt) && isIdentChar(stmt[i]) {
+		i++
+	}
+	if i < len(stmt) && strings.IndexByte("$%!#", stmt[i]) != -1 {
+		i++
+	}
+	if i < len(stmt) && stmt[i] == '(' {
+		depth := 0
+		for ; i < len(stmt); i++ {
+			if stmt[i] == '(' {
+				depth++
+			} else if stmt[i] == ')' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		if i == len(stmt) {
+			// unbalanced parentheses
+			return false
+		}
+		i++
+	}
+	return strings.HasPrefix(strings.TrimLeft(stmt[i:], " \t"), "=")
+}
+
+// remainderOffset returns where the remainder of a statement, what follows
+// the command and any spaces, starts in line.
+func remainderOffset(line string) int {
+	start := len("?")
+	if !strings.HasPrefix(line, "?") {
+		if _, start = getCommandIdx(line); start == -1 {
+			return len(line)
+		}
+	}
+	return start + len(line[start:]) - len(strings.TrimLeft(line[start:], " \t"))
+}
+
+func parseStatement(lineNumber int, line string) (Instructioner, error) {
+	var (
+		instruction Instructioner
+		err         error
+	)
+	if strings.HasPrefix(line, "?") {
+		// ? is short for PRINT
+		line = "PRINT " + line[1:]
+	}
+	cmd, cmdIdx := getCommandIdx(line)
+	remainder := ""
+	if cmdIdx != -1 {
+		remainder = strings.TrimSpace(line[cmdIdx:])
+	}
+	if cmd == "PRINT" {
+		if strings.HasPrefix(remainder, "USING") {
+			instruction, err = NewPrintUsingInstruction(lineNumber, remainder[len("USING"):])
+		} else {
+			instruction, err = NewPrintInstruction(lineNumber, remainder)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "LPRINT" {
+		instruction, err = NewLprintInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "WRITE" {
+		instruction, err = NewWriteInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "WIDTH" {
+		instruction, err = NewWidthInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "COLOR" {
+		instruction, err = NewColorInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "CLS" {
+		instruction = ClsInstruction{}
+	}
+	if cmd == "INPUT" {
+		instruction, err = NewInputInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "OPEN" {
+		instruction, err = NewOpenInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "CLOSE" {
+		instruction, err = NewCloseInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "FIELD" {
+		instruction, err = NewFieldInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "GET" || cmd == "PUT" {
+		instruction, err = NewRecordInstruction(cmd == "PUT", remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "DEF" {
+		if strings.HasPrefix(remainder, "USR") {
+			instruction, err = NewDefUsrInstruction(lineNumber, remainder)
+		} else {
+			instruction, err = NewDefFnInstruction(lineNumber, remainder)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "SEEK" {
+		instruction, err = NewSeekInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "STOP" {
+		instruction = StopInstruction{}
+	}
+	if cmd == "SLEEP" || cmd == "WAIT" {
+		instruction, err = NewSleepInstruction(lineNumber, remainder, cmd == "WAIT")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "OPTION" {
+		instruction, err = NewOptionBaseInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "DEG" {
+		instruction = DegInstruction{}
+	}
+	if cmd == "RAD" {
+		instruction = RadInstruction{}
+	}
+	if cmd == "CLEAR" {
+		instruction = ClearInstruction{}
+	}
+	if cmd == "CONST" {
+		instruction, err = NewConstInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "ON" {
+		instruction, err = NewOnErrorInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "RESUME" {
+		instruction, err = NewResumeInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "POKE" {
+		instruction, err = NewPokeInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "BSAVE" {
+		instruction, err = NewBsaveInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "BLOAD" {
+		instruction, err = NewBloadInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "REM" {
+		instruction = RemInstruction{Text: remainder}
+	}
+	if cmd == "TRON" {
+		instruction = TronInstruction{}
+	}
+	if cmd == "TROFF" {
+		instruction = TroffInstruction{}
+	}
+	if cmd == "LET" {
+		if strings.HasPrefix(remainder, "MID$(") {
+			instruction, err = NewMidInstruction(lineNumber, remainder)
+		} else {
+			instruction, err = NewLetInstruction(lineNumber, remainder)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "GOSUB" {
+		instruction, err = NewGosubInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "RETURN" {
+		instruction = ReturnInstruction{}
+	}
+	if cmd == "FOR" {
+		instruction, err = NewForInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "NEXT" {
+		instruction, err = NewNextInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "DATA" {
+		instruction, err = NewDataInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "READ" {
+		instruction, err = NewReadInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "RESTORE" {
+		instruction, err = NewRestoreInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "DIM" {
+		instruction, err = NewDimInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "SWAP" {
+		instruction, err = NewSwapInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "IF" {
+		instruction, err = NewIfInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd == "GOTO" && remainder != "" && isIdentStart(remainder[0]) {
+		instruction, err = NewGotoLabelInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	} else if cmd == "GOTO" {
+		instruction, err = NewJumpInstruction(lineNumber, remainder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if instruction == nil {
+		return nil, fmt.Errorf("unknown instruction: `%s` `%s`", cmd, remainder)
+	}
+	return instruction, nil
+}
+
+// invalidate throws away the instruction index so it is rebuilt from
+// Instructions on next use. Anything that adds or removes lines must call it.
+func (bob *Interpreter) invalidate() {
+	bob.intructionIndex = nil
+	// the program starts again from the top so it cannot be continued
+	bob.canContinue = false
+}
+
+func (bob *Interpreter) buildInstructionIndex() error {
+	if bob.intructionIndex != nil {
+		return nil
+	}
+	bob.intructionIndex = make([]int, 0, len(bob.Instructions))
+	for ln := range bob.Instructions {
+		bob.intructionIndex = append(bob.intructionIndex, ln)
+	}
+	sort.Ints(bob.intructionIndex)
+	for i := 1; i < len(bob.intructionIndex); i++ {
+		if bob.intructionIndex[i-1] == bob.intructionIndex[i] {
+			return fmt.Errorf("duplicate linenumber %v found", bob.intructionIndex[i])
+		}
+	}
+	if err := bob.buildLabels(); err != nil {
+		bob.intructionIndex = nil
+		return err
+	}
+	bob.pc = 0
+	return nil
+}
+
+// SetPC moves execution to the given line number.
+func (bob *Interpreter) SetPC(linenumber int) error {
+	bob.buildInstructionIndex()
+	idx := sort.SearchInts(bob.intructionIndex, linenumber)
+	if len(bob.intructionIndex) == idx || bob.intructionIndex[idx] != linenumber {
+		return fmt.Errorf("did not find line number: %v", linenumber)
+	}
+	bob.pc = idx
+	return nil
+}
+
+// CurrentLine returns the line number of the instruction that will be
+// executed by the next call to Step, or -1 if the program has ended.
+func (bob *Interpreter) CurrentLine() int {
+	bob.buildInstructionIndex()
+	if bob.pc >= len(bob.intructionIndex) {
+		return -1
+	}
+	return bob.intructionIndex[bob.pc]
+}
+
+// Step executes exactly the instruction at the current pc, and reports
+// whether the program has ended.
+func (bob *Interpreter) Step() (done bool, err error) {
+	if err = bob.buildInstructionIndex(); err != nil {
+		return true, err
+	}
+	if bob.pc >= len(bob.intructionIndex) {
+		return true, nil
+	}
+	bob.atBreakpoint = false
+	ln := bob.intructionIndex[bob.pc]
+	if bob.trace {
+		if _, err = fmt.Fprintf(bob.TraceOutput, "[%d]", ln); err != nil {
+			return true, err
+		}
+	}
+	bob.pc++
+	if bob.TrackCoverage {
+		if bob.covered == nil {
+			bob.covered = map[int]int{}
+		}
+		bob.covered[ln]++
+	}
+	instruction := bob.Instructions[ln]
+	if err = instruction.Execute(bob); err != nil {
+		if bob.trapError(ln, err) {
+			return false, nil
+		}
+		return true, err
+	}
+	return bob.pc >= len(bob.intructionIndex), nil
+}
+
+// Coverage returns the number of times each line has been executed while
+// TrackCoverage was set. Lines that have not been executed are left out.
+func (bob *Interpreter) Coverage() map[int]int {
+	cov := make(map[int]int, len(bob.covered))
+	for ln, n := range bob.covered {
+		cov[ln] = n
+	}
+	return cov
+}
+
+// SetBreakpoint makes Run stop before executing the given line.
+func (bob *Interpreter) SetBreakpoint(line int) {
+	if bob.breakpoints == nil {
+		bob.breakpoints = map[int]bool{}
+	}
+	bob.breakpoints[line] = true
+}
+
+// ClearBreakpoint removes a breakpoint set by SetBreakpoint.
+func (bob *Interpreter) ClearBreakpoint(line int) {
+	delete(bob.breakpoints, line)
+}
+
+// Run executes the program until it ends. If a breakpoint is reached Run
+// returns ErrBreakpoint before executing that line; calling Run again resumes
+// from the breakpoint. Calling Run once the program has ended runs it again
+// from the start.
+func (bob *Interpreter) Run() error {
+	return bob.RunContext(context.Background())
+}
+
+// Continue resumes a program halted by STOP or a breakpoint from where it
+// stopped, with its variables as they are now. It is an error to continue a
+// program that has not been halted, has ended or has been changed since.
+func (bob *Interpreter) Continue() error {
+	if !bob.canContinue {
+		return fmt.Errorf("can't continue")
+	}
+	if bob.CurrentLine() == -1 {
+		// stopped on the last line
+		bob.canContinue = false
+		return nil
+	}
+	return bob.run(context.Background(), 0)
+}
+
+// RunContext is Run, stopping with an error if ctx is cancelled.
+func (bob *Interpreter) RunContext(ctx context.Context) error {
+	return bob.run(ctx, 0)
+}
+
+// RunWithLimit is Run, stopping with ErrExecutionLimit once maxSteps
+// instructions have been executed. This stops runaway programs such as
+// `10 GOTO 10` from running forever.
+func (bob *Interpreter) RunWithLimit(maxSteps int) error {
+	return bob.run(context.Background(), maxSteps)
+}
+
+// RunSteps executes at most n instructions and reports whether the program
+// has ended, so a program can be run a slice at a time, for example once per
+// frame of a game loop. The next call carries on where the last one stopped.
+// Breakpoints are not checked.
+func (bob *Interpreter) RunSteps(n int) (done bool, err error) {
+	if bob.CurrentLine() == -1 {
+		return true, nil
+	}
+	for i := 0; i < n; i++ {
+		if done, err = bob.Step(); done || err != nil {
+			return done, err
+		}
+	}
+	return false, nil
+}
+
+// run executes the program until it ends, ctx is cancelled or, if maxSteps is
+// not 0, maxSteps instructions have been executed.
+func (bob *Interpreter) run(ctx context.Context, maxSteps int) error {
+	if bob.CurrentLine() == -1 {
+		// The program has ended, so run it again from the top.
+		bob.pc = 0
+		bob.returnStack, bob.loopStack = nil, nil
+		bob.data = dataPointer{}
+		bob.errHandler, bob.inErrHandler = 0, false
+		bob.constants = nil
+		bob.outputBytes = 0
+	}
+	for steps, resume := 0, bob.atBreakpoint; ; steps, resume = steps+1, false {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("context cancelled: %w", err)
+		}
+		if maxSteps > 0 && steps >= maxSteps {
+			return ErrExecutionLimit
+		}
+		if !resume && bob.breakpoints[bob.CurrentLine()] {
+			bob.atBreakpoint = true
+			bob.canContinue = true
+			return ErrBreakpoint
+		}
+		done, err := bob.Step()
+		bob.canContinue = errors.Is(err, ErrStop)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// DumpMemory writes the DumpString listing of the program and variables to
+// Output.
+func (bob *Interpreter) DumpMemory() error {
+	_, err := io.WriteString(bob.Output, bob.DumpString())
+	return err
+}
+
+// DumpString returns a listing of the program and the current variables.
+//
+// Variables are listed in a fixed order: by name without the type suffix,
+// and variables that share a name by suffix in the order none, %, !, # then
+// $, so A, A%, A$, B comes out the same every time. Arrays are listed after
+// all the other variables, in the same order.
+func (bob *Interpreter) DumpString() string {
+	var buf strings.Builder
+	buf.WriteString("Instructions:\n")
+	bob.buildInstructionIndex()
+	zeroFill := 0
+	if n := len(bob.intructionIndex); n > 0 {
+		zeroFill = 0 - (int(math.Log10(float64(bob.intructionIndex[n-1]))) + 1)
+	}
+	for _, key := range bob.intructionIndex {
+		ins := bob.Instructions[key]
+		if ins == nil {
+			fmt.Fprintf(&buf, "%*d nil instruction\n", zeroFill, key)
+			continue
+		}
+		fmt.Fprintf(&buf, "%*d %s\n", zeroFill, key, ins)
+	}
+
+	bob.lockVars()
+	defer bob.unlockVars()
+	maxNameLen := 0
+	names := make([]string, 0, len(bob.Variables))
+
+	for name := range bob.Variables {
+		if maxNameLen < len(name) {
+			maxNameLen = len(name)
+		}
+		names = append(names, name)
+	}
+	sortVarNames(names)
+	buf.WriteString("Variables:\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "% *s : %s\n", maxNameLen, name, bob.Variables[name].String())
+	}
+
+	arrays := make([]string, 0, len(bob.Arrays))
+	for name := range bob.Arrays {
+		arrays = append(arrays, name)
+	}
+	sortVarNames(arrays)
+	for _, name := range arrays {
+		fmt.Fprintf(&buf, "% *s%s\n", maxNameLen, name, bob.Arrays[name])
+	}
+
+	buf.WriteString("done\n")
+	return buf.String()
+}
+
+// sortVarNames sorts variable names into the order DumpMemory lists them in.
+func sortVarNames(names []string) {
+	split := func(name string) (string, int) {
+		if n := len(name); n > 0 {
+			if rank := strings.IndexByte("%!#$", name[n-1]); rank != -1 {
+				return name[:n-1], rank + 1
+			}
+		}
+		return name, 0
+	}
+	sort.Slice(names, func(i, j int) bool {
+		bi, ri := split(names[i])
+		bj, rj := split(names[j])
+		if bi != bj {
+			return bi < bj
+		}
+		return ri < rj
+	})
+}
+
+// NewInterpreter returns an empty Interpreter writing to os.Stdout, with
+// DefaultMemorySize bytes of simulated memory.
+func NewInterpreter() *Interpreter {
+	return NewInterpreterWithMemory(DefaultMemorySize)
+}
+
+// NewInterpreterWithMemory returns an empty Interpreter writing to os.Stdout,
+// with size bytes of simulated memory for PEEK and POKE.
+func NewInterpreterWithMemory(size int) *Interpreter {
+	return &Interpreter{
+		Instructions:      map[int]Instructioner{},
+		Variables:         map[string]Value{},
+		Functions:         map[string]UserFunction{},
+		Output:            os.Stdout,
+		PrinterOutput:     io.Discard,
+		TraceOutput:       os.Stderr,
+		Input:             os.Stdin,
+		Files:             OSFileSystem{},
+		InputPromptSuffix: "? ",
+		IntSize:           32,
+		Sleep:             time.Sleep,
+		Now:               time.Now,
+		MaxCallDepth:      defaultMaxCallDepth,
+		zoneWidth:         defaultZoneWidth,
+		memory:            make([]byte, size),
+	}
+}
+
+// Clone returns a copy of the interpreter with the same program and settings
+// but fresh runtime state: no variables, arrays or DEF FN functions, no open
+// files, zeroed memory and execution back at the start. The clone shares
+// Output, Input and the other writers and readers, set them before running
+// the clone alongside the original. Instructions are never changed once
+// parsed, so the clone can share them and be run in another goroutine.
+func (bob *Interpreter) Clone() *Interpreter {
+	c := NewInterpreterWithMemory(len(bob.memory))
+	for ln, ins := range bob.Instructions {
+		c.Instructions[ln] = ins
+	}
+	if bob.source != nil {
+		c.source = make(map[int]string, len(bob.source))
+		for ln, raw := range bob.source {
+			c.source[ln] = raw
+		}
+	}
+	for ln := range bob.breakpoints {
+		c.SetBreakpoint(ln)
+	}
+	for name, fn := range bob.hostFuncs {
+		c.RegisterFuncEx(name, fn)
+	}
+	c.Output = bob.Output
+	c.PrinterOutput = bob.PrinterOutput
+	c.TraceOutput = bob.TraceOutput
+	c.Input = bob.Input
+	c.Files = bob.Files
+	c.InputPromptSuffix = bob.InputPromptSuffix
+	c.Terminal = bob.Terminal
+	c.TabNewline = bob.TabNewline
+	c.IntSize = bob.IntSize
+	c.DivZeroZero = bob.DivZeroZero
+	c.NoEscapes = bob.NoEscapes
+	c.Sleep = bob.Sleep
+	c.Now = bob.Now
+	c.Frozen = bob.Frozen
+	c.KeepSource = bob.KeepSource
+	c.StrictVars = bob.StrictVars
+	c.TrackCoverage = bob.TrackCoverage
+	c.MaxCallDepth = bob.MaxCallDepth
+	c.MaxOutputBytes = bob.MaxOutputBytes
+	c.StringMemory = bob.StringMemory
+	c.AngleMode = bob.AngleMode
+	c.ThreadSafe = bob.ThreadSafe
+	c.screen.width = bob.screen.width
+	c.zoneWidth = bob.zoneWidth
+	c.printer.width = bob.printer.width
+	c.FixedRandom(bob.fixedRnd)
+	return c
+}
@@ -0,0 +1,743 @@
+package gdbasic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestInterpreter returns an interpreter with the lines interpreted, its
+// output going to the returned buffer and its input empty.
+func newTestInterpreter(t *testing.T, lines ...string) (*Interpreter, *bytes.Buffer) {
+	t.Helper()
+	bob := NewInterpreter()
+	out := new(bytes.Buffer)
+	bob.Output = out
+	bob.Input = strings.NewReader("")
+	for _, line := range lines {
+		if err := bob.Interpret(line); err != nil {
+			t.Fatalf("Interpret(%q): %v", line, err)
+		}
+	}
+	return bob, out
+}
+
+// runProgram runs the lines as a program and returns what it printed.
+func runProgram(t *testing.T, lines ...string) string {
+	t.Helper()
+	bob, out := newTestInterpreter(t, lines...)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return out.String()
+}
+
+func TestStep(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A=1`,
+		`20 LET A=A+1`,
+		`30 PRINT A`,
+	)
+	type step struct {
+		line int
+		a    int
+		done bool
+	}
+	// line is the line about to be executed, a the value of A after it is
+	steps := []step{
+		{line: 10, a: 1},
+		{line: 20, a: 2},
+		{line: 30, a: 2, done: true},
+	}
+	for i, s := range steps {
+		if got := bob.CurrentLine(); got != s.line {
+			t.Fatalf("step %d: CurrentLine() = %d, want %d", i, got, s.line)
+		}
+		done, err := bob.Step()
+		if err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		if done != s.done {
+			t.Errorf("step %d: done = %v, want %v", i, done, s.done)
+		}
+		if a := bob.Variables["A"]; a.Int != s.a {
+			t.Errorf("step %d: A = %v, want %d", i, a, s.a)
+		}
+	}
+	if got := bob.CurrentLine(); got != -1 {
+		t.Errorf("CurrentLine() at the end = %d, want -1", got)
+	}
+	if done, err := bob.Step(); !done || err != nil {
+		t.Errorf("Step() at the end = %v, %v, want true, nil", done, err)
+	}
+	if got := out.String(); got != "2\n" {
+		t.Errorf("output = %q, want %q", got, "2\n")
+	}
+}
+
+func TestStepError(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`10 LET A=1/0`,
+		`20 PRINT "NOT REACHED"`,
+	)
+	done, err := bob.Step()
+	if err == nil {
+		t.Fatal("expected a division by zero error")
+	}
+	if !done {
+		t.Error("a failed step should end the program")
+	}
+}
+
+func TestStepEmptyProgram(t *testing.T) {
+	bob := NewInterpreter()
+	done, err := bob.Step()
+	if !done || err != nil {
+		t.Errorf("Step() = %v, %v, want true, nil", done, err)
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A=1`,
+		`20 LET A=2`,
+		`30 PRINT A`,
+	)
+	bob.SetBreakpoint(20)
+	if err := bob.Run(); err != ErrBreakpoint {
+		t.Fatalf("Run() = %v, want ErrBreakpoint", err)
+	}
+	if got := bob.CurrentLine(); got != 20 {
+		t.Errorf("stopped at line %d, want 20", got)
+	}
+	if a := bob.Variables["A"]; a.Int != 1 {
+		t.Errorf("A = %v at the breakpoint, want 1", a)
+	}
+	if out.Len() != 0 {
+		t.Errorf("printed %q before the breakpoint", out)
+	}
+
+	// Run again resumes from the breakpoint rather than stopping on it.
+	if err := bob.Run(); err != nil {
+		t.Fatalf("resumed Run() = %v", err)
+	}
+	if got := out.String(); got != "2\n" {
+		t.Errorf("output = %q, want %q", got, "2\n")
+	}
+
+	// Once ended the program is run from the start, stopping again.
+	if err := bob.Run(); err != ErrBreakpoint {
+		t.Fatalf("rerun Run() = %v, want ErrBreakpoint", err)
+	}
+	bob.ClearBreakpoint(20)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run() after ClearBreakpoint = %v", err)
+	}
+}
+
+func TestBreakpointInLoop(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`10 FOR I=1 TO 3`,
+		`20 LET A=I`,
+		`30 NEXT I`,
+	)
+	bob.SetBreakpoint(20)
+	hits := 0
+	for {
+		err := bob.Run()
+		if err == nil {
+			break
+		}
+		if err != ErrBreakpoint {
+			t.Fatalf("Run() = %v", err)
+		}
+		hits++
+		if i := bob.Variables["I"]; i.Int != hits {
+			t.Errorf("hit %d: I = %v", hits, i)
+		}
+	}
+	if hits != 3 {
+		t.Errorf("breakpoint hit %d times, want 3", hits)
+	}
+}
+
+func TestRunWithLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		program  []string
+		maxSteps int
+		err      error
+	}{
+		{"runaway loop", []string{`10 GOTO 10`}, 100, ErrExecutionLimit},
+		{"ends within the limit", []string{`10 LET A=1`, `20 LET A=2`, `30 LET A=3`}, 3, nil},
+		{"one step short", []string{`10 LET A=1`, `20 LET A=2`, `30 LET A=3`}, 2, ErrExecutionLimit},
+		{"no limit", []string{`10 FOR I=1 TO 1000`, `20 NEXT I`}, 0, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tc.program...)
+			if err := bob.RunWithLimit(tc.maxSteps); err != tc.err {
+				t.Errorf("RunWithLimit(%d) = %v, want %v", tc.maxSteps, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 GOTO 10`)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bob.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunContext() = %v, want a deadline exceeded error", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	bob, out := newTestInterpreter(t, `10 PRINT "NOT REACHED"`)
+	if err := bob.RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunContext() = %v, want a cancelled error", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("printed %q after being cancelled", out)
+	}
+}
+
+func TestEditAfterRun(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 PRINT "A"`,
+		`30 PRINT "C"`,
+	)
+	// each line is typed in after running the program as it was
+	steps := []struct {
+		line string
+		want string
+		err  bool
+	}{
+		{line: "", want: "A\nC\n"},
+		{line: `20 PRINT "B"`, want: "A\nB\nC\n"},
+		{line: `30 PRINT "D"`, want: "A\nB\nD\n"},
+		{line: `5 GOTO 30`, want: "D\n"},
+		// deleting the GOTO target
+		{line: `30`, err: true},
+	}
+	for _, s := range steps {
+		if s.line != "" {
+			if err := bob.Interpret(s.line); err != nil {
+				t.Fatalf("Interpret(%q): %v", s.line, err)
+			}
+		}
+		out.Reset()
+		err := bob.Run()
+		if s.err {
+			if err == nil {
+				t.Errorf("after %q: expected an error", s.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("after %q: Run: %v", s.line, err)
+		}
+		if got := out.String(); got != s.want {
+			t.Errorf("after %q: printed %q, want %q", s.line, got, s.want)
+		}
+	}
+}
+
+func TestSortVarNames(t *testing.T) {
+	names := []string{"B", "A$", "AB", "A#", "A", "A!", "A%", "B%"}
+	sortVarNames(names)
+	want := []string{"A", "A%", "A!", "A#", "A$", "AB", "B", "B%"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("sorted %v, want %v", names, want)
+	}
+}
+
+func TestDumpString(t *testing.T) {
+	program := []string{
+		`10 DIM B(2), A$(1)`,
+		`20 LET B$="X"`,
+		`30 LET A%=1`,
+		`40 LET B=2`,
+		`50 LET A=3`,
+		`60 LET B(1)=5`,
+		`70 LET A$(1)="Y"`,
+	}
+	bob, _ := newTestInterpreter(t, program...)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// scalars first, then arrays, each in sortVarNames order
+	want := "Instructions:\n" + strings.Join(program, "\n") + "\n" + strings.Join([]string{
+		`Variables:`,
+		` A : 3`,
+		`A% : 1`,
+		` B : 2`,
+		`B$ : "X"`,
+		`A$(1) ["" "Y"]`,
+		` B(2) [0 5 0]`,
+		`done`,
+	}, "\n") + "\n"
+	for i := 0; i < 3; i++ {
+		if got := bob.DumpString(); got != want {
+			t.Fatalf("dump %d got:\n%s\nwant:\n%s", i, got, want)
+		}
+	}
+}
+
+func TestDumpStringNilInstruction(t *testing.T) {
+	bob, out := newTestInterpreter(t, `10 PRINT "A"`, `200 GOTO 10`)
+	bob.Instructions[20] = nil
+	bob.invalidate()
+	// line numbers are left aligned to the width of the highest
+	want := strings.Join([]string{
+		`Instructions:`,
+		`10  PRINT"A"`,
+		`20  nil instruction`,
+		`200 GOTO 10`,
+		`Variables:`,
+		`done`,
+	}, "\n") + "\n"
+	if got := bob.DumpString(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if err := bob.DumpMemory(); err != nil {
+		t.Fatalf("DumpMemory: %v", err)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("DumpMemory wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExecuteDirect(t *testing.T) {
+	bob, out := newTestInterpreter(t, `10 PRINT A`)
+	steps := []struct {
+		line string
+		want string
+	}{
+		{`PRINT 2+3`, "5\n"},
+		{`LET A=7`, ""},
+		{`PRINT A`, "7\n"},
+		{`  `, ""},
+		{`20 PRINT A*2`, ""},
+	}
+	for _, s := range steps {
+		out.Reset()
+		if err := bob.Execute(s.line); err != nil {
+			t.Fatalf("Execute(%q): %v", s.line, err)
+		}
+		if got := out.String(); got != s.want {
+			t.Errorf("Execute(%q) printed %q, want %q", s.line, got, s.want)
+		}
+	}
+	// direct lines are not stored, numbered ones are
+	if got, want := listing(t, bob), "10 PRINT A\n20 PRINT A*2\n"; got != want {
+		t.Errorf("listing:\n%s\nwant:\n%s", got, want)
+	}
+	// the program sees what was set in direct mode
+	out.Reset()
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got, want := out.String(), "7\n14\n"; got != want {
+		t.Errorf("Run printed %q, want %q", got, want)
+	}
+}
+
+func TestExecuteDirectGoto(t *testing.T) {
+	bob, out := newTestInterpreter(t, `10 PRINT "A"`, `20 PRINT "B"`)
+	if err := bob.Execute(`GOTO 20`); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := out.String(); got != "B\n" {
+		t.Errorf("printed %q, want %q", got, "B\n")
+	}
+	for _, line := range []string{`GOTO 99`, `NONSENSE`, `PRINT (`} {
+		if err := bob.Execute(line); err == nil {
+			t.Errorf("Execute(%q): expected an error", line)
+		}
+	}
+}
+
+func TestStopContinue(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A=1`,
+		`20 STOP`,
+		`30 PRINT A`,
+	)
+	if err := bob.Run(); !errors.Is(err, ErrStop) {
+		t.Fatalf("Run() = %v, want ErrStop", err)
+	}
+	if got := bob.CurrentLine(); got != 30 {
+		t.Errorf("stopped before line %d, want 30", got)
+	}
+	if a, ok := bob.GetVar("A"); !ok || a.Int != 1 {
+		t.Errorf("GetVar(A) = %v, %v at the STOP, want 1, true", a, ok)
+	}
+	if err := bob.SetVar("A", Value{Int: 42}); err != nil {
+		t.Fatalf("SetVar: %v", err)
+	}
+	if err := bob.Continue(); err != nil {
+		t.Fatalf("Continue: %v", err)
+	}
+	if got := out.String(); got != "42\n" {
+		t.Errorf("printed %q, want %q", got, "42\n")
+	}
+	if err := bob.Continue(); err == nil {
+		t.Error("Continue after the program ended: expected an error")
+	}
+}
+
+func TestContCommand(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A=1`,
+		`20 STOP`,
+		`30 PRINT A`,
+	)
+	if err := bob.Run(); !errors.Is(err, ErrStop) {
+		t.Fatalf("Run() = %v, want ErrStop", err)
+	}
+	for _, line := range []string{`LET A=A+1`, `CONT`} {
+		if err := bob.Execute(line); err != nil {
+			t.Fatalf("Execute(%q): %v", line, err)
+		}
+	}
+	if got := out.String(); got != "2\n" {
+		t.Errorf("printed %q, want %q", got, "2\n")
+	}
+}
+
+func TestContinueBreakpoint(t *testing.T) {
+	bob, out := newTestInterpreter(t, `10 LET A=1`, `20 PRINT A`)
+	bob.SetBreakpoint(20)
+	if err := bob.Run(); err != ErrBreakpoint {
+		t.Fatalf("Run() = %v, want ErrBreakpoint", err)
+	}
+	bob.SetVar("A", Value{Int: 5})
+	if err := bob.Continue(); err != nil {
+		t.Fatalf("Continue: %v", err)
+	}
+	if got := out.String(); got != "5\n" {
+		t.Errorf("printed %q, want %q", got, "5\n")
+	}
+}
+
+func TestContinueErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		run     bool
+		edit    string
+	}{
+		{"never run", []string{`10 PRINT "A"`}, false, ""},
+		{"ran to the end", []string{`10 PRINT "A"`}, true, ""},
+		{"changed since the STOP", []string{`10 STOP`, `20 PRINT "A"`}, true, `30 PRINT "B"`},
+		{"stopped by an error", []string{`10 LET A=1/0`, `20 PRINT "A"`}, true, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tc.program...)
+			if tc.run {
+				bob.Run()
+			}
+			if tc.edit != "" {
+				if err := bob.Interpret(tc.edit); err != nil {
+					t.Fatalf("Interpret: %v", err)
+				}
+			}
+			if err := bob.Continue(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestRunSteps(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 FOR I=1 TO 5`,
+		`20 PRINT I;`,
+		`30 NEXT I`,
+		`40 PRINT`,
+	)
+	// 1 FOR, 5 PRINTs and 5 NEXTs then the final PRINT is 12 steps
+	var chunks []string
+	for calls := 1; ; calls++ {
+		if calls > 10 {
+			t.Fatal("program did not finish")
+		}
+		done, err := bob.RunSteps(5)
+		if err != nil {
+			t.Fatalf("RunSteps: %v", err)
+		}
+		chunks = append(chunks, out.String())
+		out.Reset()
+		if done {
+			break
+		}
+	}
+	want := []string{"12", "345", "\n"}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("each call printed %q, want %q", chunks, want)
+	}
+	if done, err := bob.RunSteps(5); !done || err != nil {
+		t.Errorf("RunSteps() once ended = %v, %v, want true, nil", done, err)
+	}
+}
+
+func TestRunStepsError(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 LET A=1`, `20 LET A=1/0`, `30 PRINT A`)
+	if done, err := bob.RunSteps(5); !done || err == nil {
+		t.Errorf("RunSteps() = %v, %v, want true and an error", done, err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 INPUT N`,
+		`20 LET T=N*2`,
+		`30 PRINT T`,
+	)
+	bob.Input = strings.NewReader("1\n")
+	bob.IntSize = 16
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	c := bob.Clone()
+	var cout bytes.Buffer
+	c.Output = &cout
+	c.Input = strings.NewReader("20\n")
+	if len(c.Variables) != 0 {
+		t.Errorf("clone starts with variables %v", c.Variables)
+	}
+	if c.IntSize != 16 {
+		t.Errorf("clone IntSize = %d, want 16", c.IntSize)
+	}
+	if err := c.Run(); err != nil {
+		t.Fatalf("clone Run: %v", err)
+	}
+	if got := cout.String(); got != "? 40\n" {
+		t.Errorf("clone printed %q, want %q", got, "? 40\n")
+	}
+
+	// the original is untouched by the clone
+	if got := out.String(); got != "? 2\n" {
+		t.Errorf("original printed %q, want %q", got, "? 2\n")
+	}
+	if got := bob.Variables["T"]; got.Int != 2 {
+		t.Errorf("original T = %v, want 2", got)
+	}
+	// and edits to either program are not seen by the other
+	if err := c.Interpret(`40 PRINT "CLONE"`); err != nil {
+		t.Fatalf("Interpret: %v", err)
+	}
+	if _, ok := bob.Instructions[40]; ok {
+		t.Error("line added to the clone appeared in the original")
+	}
+}
+
+func TestCloneParallel(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`10 LET T=0`,
+		`20 FOR I=1 TO N`,
+		`30 LET T=T+I`,
+		`40 NEXT I`,
+	)
+	results := make([]int, 10)
+	done := make(chan struct{})
+	for n := range results {
+		c := bob.Clone()
+		c.Variables["N"] = Value{Int: n}
+		go func(n int) {
+			defer func() { done <- struct{}{} }()
+			if err := c.Run(); err != nil {
+				t.Errorf("clone %d: Run: %v", n, err)
+			}
+			results[n] = c.Variables["T"].Int
+		}(n)
+	}
+	for range results {
+		<-done
+	}
+	for n, got := range results {
+		if want := n * (n + 1) / 2; got != want {
+			t.Errorf("clone %d: T = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestParseErrorColumn(t *testing.T) {
+	tests := []struct {
+		line string
+		col  int
+		msg  string
+	}{
+		// columns are byte offsets from the start of the line
+		{`10 PRINT TAB(5`, 14, "missing `)`"},
+		{`10 PRINT TAB(X)`, 13, "bad column `X`"},
+		{`10 PRINT "A"; TAB(Y)`, 18, "bad column `Y`"},
+		{`10 LET A 5`, 10, "missing `=`"},
+		{`10 LET A=(1`, 11, "missing `)`"},
+		{`10 PRINT 1+`, 11, "unexpected end of expression"},
+		{`10 IF 1 THEN PRINT (`, 20, "unexpected end of expression"},
+		{`10 X: LET A=(1`, 14, "missing `)`"},
+	}
+	for _, tc := range tests {
+		err := NewInterpreter().Interpret(tc.line)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: got error %v, want a ParseError", tc.line, err)
+			continue
+		}
+		if pe.Line != 10 {
+			t.Errorf("%s: Line = %d, want 10", tc.line, pe.Line)
+		}
+		if pe.Col != tc.col {
+			t.Errorf("%s: Col = %d, want %d", tc.line, pe.Col, tc.col)
+		}
+		if !strings.Contains(pe.Msg, tc.msg) {
+			t.Errorf("%s: Msg = %q, want it to contain %q", tc.line, pe.Msg, tc.msg)
+		}
+	}
+}
+
+func TestParseErrorCaret(t *testing.T) {
+	tests := []struct {
+		line  string
+		token string
+	}{
+		{`10 LET A 5`, ``},
+		{`10 PRINT TAB(X)`, `X)`},
+		{`10 PRINT "A"; TAB(Y)`, `Y)`},
+		{`10 IF 1 THEN LET B=`, ``},
+		{`10 X: LET A=(1`, ``},
+	}
+	for _, tc := range tests {
+		err := NewInterpreter().Interpret(tc.line)
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.line)
+			continue
+		}
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) != 3 {
+			t.Errorf("%s: error %q is not 3 lines", tc.line, err)
+			continue
+		}
+		if lines[1] != tc.line {
+			t.Errorf("%s: source line is %q", tc.line, lines[1])
+		}
+		col := strings.Index(lines[2], "^")
+		if strings.TrimLeft(lines[2], " ") != "^" {
+			t.Errorf("%s: caret line is %q", tc.line, lines[2])
+		}
+		// the caret is under the bad token or just past the end of the line
+		if got := tc.line[col:]; !strings.HasPrefix(got, tc.token) || (tc.token == "" && got != "") {
+			t.Errorf("%s: caret is under %q, want %q", tc.line, got, tc.token)
+		}
+	}
+}
+
+func TestParseErrorNoSource(t *testing.T) {
+	// without the source, or with a column past it, there is no caret
+	for _, pe := range []*ParseError{
+		{Line: 10, Col: 3, Msg: "bad"},
+		{Line: 10, Col: 9, Msg: "bad", Source: "10 X"},
+	} {
+		if got, want := pe.Error(), fmt.Sprintf("line 10 col %d: bad", pe.Col); got != want {
+			t.Errorf("Error() = %q, want %q", got, want)
+		}
+	}
+	pe := &ParseError{Line: 10, Col: 4, Msg: "bad", Source: "10 X"}
+	if got, want := pe.Error(), "line 10 col 4: bad\n10 X\n    ^"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`10 FOR I=1 TO 5`,
+		`20 LET S=S+I`,
+		`30 IF I=3 THEN GOTO 50`,
+		`40 LET T=T+1`,
+		`50 NEXT I`,
+		`60 GOTO 80`,
+		`70 PRINT "NEVER"`,
+		`80 PRINT S`,
+	)
+	bob.TrackCoverage = true
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := map[int]int{10: 1, 20: 5, 30: 5, 40: 4, 50: 5, 60: 1, 80: 1}
+	if got := bob.Coverage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Coverage() = %v, want %v", got, want)
+	}
+	// counts carry on over runs
+	if err := bob.Run(); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if got := bob.Coverage()[20]; got != 10 {
+		t.Errorf("line 20 ran %d times over two runs, want 10", got)
+	}
+	// the map returned is a copy
+	bob.Coverage()[20] = 0
+	if got := bob.Coverage()[20]; got != 10 {
+		t.Errorf("changing the map from Coverage changed the count to %d", got)
+	}
+}
+
+func TestCoverageOff(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 PRINT 1`)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := bob.Coverage(); len(got) != 0 {
+		t.Errorf("Coverage() = %v without TrackCoverage, want none", got)
+	}
+	if bob.covered != nil {
+		t.Error("coverage was recorded without TrackCoverage")
+	}
+}
+
+func TestInterpretNumberOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"deletes the line", []string{`10 PRINT 1`, `20 PRINT 2`, `10`}, "20 PRINT 2\n"},
+		{"spaces around the number", []string{`10 PRINT 1`, `20 PRINT 2`, `  20  `}, "10 PRINT 1\n"},
+		{"missing line", []string{`10 PRINT 1`, `30`}, "10 PRINT 1\n"},
+		{"re-added", []string{`10 PRINT 1`, `10`, `10 PRINT 3`}, "10 PRINT 3\n"},
+		{"whitespace lines are ignored", []string{`10 PRINT 1`, ``, `   `, "\t"}, "10 PRINT 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tt.lines...)
+			if got := listing(t, bob); got != tt.want {
+				t.Errorf("listing is %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNumberOnly(t *testing.T) {
+	bob, out := newTestInterpreter(t)
+	src := "10 PRINT 1\n20 PRINT 2\n\n   \n20\n30 PRINT 3\n100"
+	if err := bob.LoadReader("X", strings.NewReader(src)); err != nil {
+		t.Fatalf("LoadReader: %v", err)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.String() != "1\n3\n" {
+		t.Errorf("printed %q, want %q", out.String(), "1\n3\n")
+	}
+}
@@ -0,0 +1,54 @@
+package gdbasic
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// jsonLine is how a line of the program is written by MarshalJSON.
+type jsonLine struct {
+	Line int    `json:"line"`
+	Type string `json:"type"`
+	// Text is the line as it is listed, which can be parsed again.
+	Text   string        `json:"text"`
+	Fields Instructioner `json:"fields"`
+}
+
+// MarshalJSON writes the program as an array of its lines in order, each
+// giving the line number, the type of instruction, such as "Let" or "Jump",
+// the listing of the instruction and the instruction's fields.
+func (bob *Interpreter) MarshalJSON() ([]byte, error) {
+	if err := bob.buildInstructionIndex(); err != nil {
+		return nil, err
+	}
+	lines := make([]jsonLine, 0, len(bob.intructionIndex))
+	for _, ln := range bob.intructionIndex {
+		ins := bob.Instructions[ln]
+		lines = append(lines, jsonLine{
+			Line:   ln,
+			Type:   instructionType(ins),
+			Text:   ins.String(),
+			Fields: ins,
+		})
+	}
+	return json.Marshal(lines)
+}
+
+// instructionType returns the name of the instruction's type without the
+// Instruction suffix.
+func instructionType(ins Instructioner) string {
+	t := reflect.TypeOf(ins)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Instruction")
+}
+
+// MarshalJSON gives the items of a PRINT, which are otherwise unexported.
+func (pi PrintInstruction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Items     []IntrepreterStringer
+		NoNewline bool
+	}{pi.strings, pi.NoNewline})
+}
@@ -0,0 +1,56 @@
+package gdbasic
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`30 GOTO 10`,
+		`10 LET A=1`,
+		`20 PRINT "HI"; A`,
+	)
+	got, err := json.Marshal(bob)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `[` +
+		`{"line":10,"type":"Let","text":"LET A=1","fields":{"VarName":"A","Subscripts":null,"Expr":{"Int":1,"Float":0,"Str":"","IsStr":false,"IsFloat":false}}},` +
+		`{"line":20,"type":"Print","text":"PRINT\"HI\";A","fields":{"Items":[{"Int":0,"Float":0,"Str":"HI","IsStr":true,"IsFloat":false},{"Expression":"A","Text":"A"}],"NoNewline":false}},` +
+		`{"line":30,"type":"Jump","text":"GOTO 10","fields":10}` +
+		`]`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestMarshalJSONText(t *testing.T) {
+	// the text of each line can be parsed back into the same program
+	bob, _ := newTestInterpreter(t,
+		`10 FOR I=1 TO 3`,
+		`20 IF I=2 THEN PRINT "TWO" ELSE PRINT I`,
+		`30 NEXT I`,
+	)
+	b, err := json.Marshal(bob)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var lines []struct {
+		Line int
+		Text string
+	}
+	if err := json.Unmarshal(b, &lines); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	again := NewInterpreter()
+	for _, l := range lines {
+		if err := again.Interpret(fmt.Sprintf("%d %s", l.Line, l.Text)); err != nil {
+			t.Fatalf("Interpret(%d %s): %v", l.Line, l.Text, err)
+		}
+	}
+	if got, want := listing(t, again), listing(t, bob); got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+}
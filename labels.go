@@ -0,0 +1,125 @@
+package gdbasic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LabeledInstruction is an instruction on a line with a label, as in
+// `start: PRINT "hi"`, that GOTO can use in place of the line number. The
+// instruction may be nil for a line with only a label.
+type LabeledInstruction struct {
+	Label string
+	Instructioner
+}
+
+func (li LabeledInstruction) Execute(intp *Interpreter) error {
+	if li.Instructioner == nil {
+		return nil
+	}
+	return li.Instructioner.Execute(intp)
+}
+
+func (li LabeledInstruction) String() string {
+	if li.Instructioner == nil {
+		return li.Label + ":"
+	}
+	return li.Label + ": " + li.Instructioner.String()
+}
+
+func (li LabeledInstruction) LineRefs() []int {
+	if lr, ok := li.Instructioner.(LineReferencer); ok {
+		return lr.LineRefs()
+	}
+	return nil
+}
+
+func (li LabeledInstruction) RenumberRefs(lines map[int]int) Instructioner {
+	if lr, ok := li.Instructioner.(LineReferencer); ok {
+		li.Instructioner = lr.RenumberRefs(lines)
+	}
+	return li
+}
+
+// unlabeled returns the instruction without its label, if it has one.
+func unlabeled(ins Instructioner) Instructioner {
+	if li, ok := ins.(LabeledInstruction); ok {
+		return li.Instructioner
+	}
+	return ins
+}
+
+// labelRefs returns the labels gone to by ins, including those gone to from
+// the branches of an IF.
+func labelRefs(ins Instructioner) []string {
+	switch ins := unlabeled(ins).(type) {
+	case GotoLabelInstruction:
+		return []string{string(ins)}
+	case *IfInstruction:
+		return labelRefs(*ins)
+	case IfInstruction:
+		return append(labelRefs(ins.Then), labelRefs(ins.Else)...)
+	}
+	return nil
+}
+
+// cutLabel splits a label, a name followed by a colon, off the start of a
+// statement.
+func cutLabel(stmt string) (label, rest string, ok bool) {
+	if len(stmt) == 0 || !isIdentStart(stmt[0]) {
+		return "", stmt, false
+	}
+	i := 1
+	for i < len(stmt) && isIdentChar(stmt[i]) {
+		i++
+	}
+	if i == len(stmt) || stmt[i] != ':' {
+		return "", stmt, false
+	}
+	return stmt[:i], stmt[i+1:], true
+}
+
+// buildLabels maps the labels of the program to their line numbers. A label
+// may only be used once.
+func (bob *Interpreter) buildLabels() error {
+	bob.labels = nil
+	for _, ln := range bob.intructionIndex {
+		li, ok := bob.Instructions[ln].(LabeledInstruction)
+		if !ok {
+			continue
+		}
+		if bob.labels == nil {
+			bob.labels = map[string]int{}
+		}
+		if first, ok := bob.labels[li.Label]; ok {
+			return fmt.Errorf("duplicate label %v on lines %v and %v", li.Label, first, ln)
+		}
+		bob.labels[li.Label] = ln
+	}
+	return nil
+}
+
+// GotoLabelInstruction is a GOTO to the line with the label.
+type GotoLabelInstruction string
+
+func (gl GotoLabelInstruction) Execute(intp *Interpreter) error {
+	line, ok := intp.labels[string(gl)]
+	if !ok {
+		return fmt.Errorf("did not find label: %v", string(gl))
+	}
+	return intp.SetPC(line)
+}
+
+func (gl GotoLabelInstruction) String() string {
+	return fmt.Sprintf("GOTO %v", string(gl))
+}
+
+func NewGotoLabelInstruction(_ int, remainder string) (GotoLabelInstruction, error) {
+	label := strings.TrimSpace(remainder)
+	for i := 0; i < len(label); i++ {
+		if !isIdentChar(label[i]) {
+			return "", fmt.Errorf("goto has a bad label `%s`", label)
+		}
+	}
+	return GotoLabelInstruction(label), nil
+}
@@ -0,0 +1,126 @@
+package gdbasic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGotoLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{"forward", []string{
+			`10 GOTO skip`,
+			`20 PRINT "SKIPPED"`,
+			`30 skip: PRINT "HERE"`,
+		}, "HERE\n"},
+		{"backward", []string{
+			`10 top: LET N=N+1`,
+			`20 IF N<3 THEN GOTO top`,
+			`30 PRINT N`,
+		}, "3\n"},
+		{"label on its own", []string{
+			`10 GOTO done`,
+			`20 PRINT "SKIPPED"`,
+			`30 done:`,
+			`40 PRINT "DONE"`,
+		}, "DONE\n"},
+		{"else branch", []string{
+			`10 IF 0 THEN PRINT "NO" ELSE GOTO yes`,
+			`20 PRINT "SKIPPED"`,
+			`30 yes: PRINT "YES"`,
+		}, "YES\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runProgram(t, tt.program...); got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGotoLabelErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		err     string
+	}{
+		{"unknown label", []string{`10 GOTO nowhere`}, "nowhere"},
+		{"duplicate label", []string{`10 here: PRINT 1`, `20 here: PRINT 2`}, "here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tt.program...)
+			err := bob.Run()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Run() = %v, want an error about %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		missing []string
+	}{
+		{"valid", []string{
+			`10 start: GOTO done`,
+			`20 IF A THEN GOTO start ELSE GOTO done`,
+			`30 done: PRINT 1`,
+		}, nil},
+		{"goto", []string{`10 GOTO nolabel`}, []string{
+			"line 10 refers to missing label nolabel",
+		}},
+		{"labeled goto", []string{`10 here: GOTO nolabel`}, []string{
+			"line 10 refers to missing label nolabel",
+		}},
+		{"then", []string{`30 IF 1 THEN GOTO nolabel`}, []string{
+			"line 30 refers to missing label nolabel",
+		}},
+		{"else", []string{`30 IF 1 THEN PRINT 1 ELSE GOTO other`}, []string{
+			"line 30 refers to missing label other",
+		}},
+		{"nested if", []string{`30 IF 1 THEN IF 2 THEN GOTO a ELSE GOTO b`}, []string{
+			"line 30 refers to missing label a",
+			"line 30 refers to missing label b",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tt.program...)
+			err := bob.Validate()
+			if len(tt.missing) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error reporting %q", tt.missing)
+			}
+			for _, msg := range tt.missing {
+				if !strings.Contains(err.Error(), msg) {
+					t.Errorf("error %q does not report %q", err, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestLabelListing(t *testing.T) {
+	program := []string{
+		`10 start: PRINT"HI"`,
+		`20 done:`,
+		`30 GOTO start`,
+		`40 IF A THEN GOTO done`,
+	}
+	bob, _ := newTestInterpreter(t, program...)
+	if got, want := listing(t, bob), strings.Join(program, "\n")+"\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package gdbasic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// includeDirective is the comment that makes Load read in another file.
+const includeDirective = "$INCLUDE:"
+
+// Load reads a program from the named file using Files, interpreting each
+// line. A line ending in _ is continued on the next line. A line with the
+// comment REM $INCLUDE: "lib.bas" has the lines of lib.bas loaded after it,
+// a file can not include itself, directly or through another file.
+func (bob *Interpreter) Load(name string) error {
+	return bob.load(name, nil)
+}
+
+// LoadReader is Load for a program read from r, name is used in errors.
+// Included files are still opened using Files.
+func (bob *Interpreter) LoadReader(name string, r io.Reader) error {
+	return bob.loadReader(name, r, nil)
+}
+
+func (bob *Interpreter) load(name string, including []string) error {
+	for _, inc := range including {
+		if inc == name {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(including, " -> "), name)
+		}
+	}
+	file, err := bob.Files.OpenFile(name, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return bob.loadReader(name, file, including)
+}
+
+func (bob *Interpreter) loadReader(name string, r io.Reader, including []string) error {
+	including = append(including, name)
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := bob.Interpret(line); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if inc, ok := includeName(line); ok {
+			if err := bob.load(inc, including); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// includeName returns the file named by a REM $INCLUDE: line. The name
+// may be in double or single quotes.
+func includeName(line string) (string, bool) {
+	_, stmt, ok := strings.Cut(strings.TrimSpace(line), " ")
+	if !ok {
+		return "", false
+	}
+	stmt = strings.TrimSpace(stmt)
+	cmd, idx := getCommandIdx(stmt)
+	if cmd != "REM" || idx == -1 {
+		return "", false
+	}
+	text := strings.TrimSpace(stmt[idx:])
+	if !strings.HasPrefix(strings.ToUpper(text), includeDirective) {
+		return "", false
+	}
+	text = strings.TrimSpace(text[len(includeDirective):])
+	if len(text) < 2 || (text[0] != '"' && text[0] != '\'') || text[len(text)-1] != text[0] {
+		return "", false
+	}
+	return text[1 : len(text)-1], true
+}
+
+// lineScanner reads the lines of a program, joining a line that ends in an
+// underscore to the one after it so a long statement can be split over
+// several lines. The joined line has the line number of the first.
+type lineScanner struct {
+	*bufio.Scanner
+	line string
+}
+
+func newLineScanner(r io.Reader) *lineScanner {
+	return &lineScanner{Scanner: bufio.NewScanner(r)}
+}
+
+func (ls *lineScanner) Scan() bool {
+	ls.line = ""
+	more := false
+	for ls.Scanner.Scan() {
+		more = true
+		line := strings.TrimRight(ls.Scanner.Text(), " \t")
+		if !strings.HasSuffix(line, "_") {
+			ls.line += line
+			return true
+		}
+		ls.line += line[:len(line)-1]
+	}
+	// a continued last line is still a line
+	return more && ls.Scanner.Err() == nil
+}
+
+func (ls *lineScanner) Text() string { return ls.line }
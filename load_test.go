@@ -0,0 +1,187 @@
+package gdbasic
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newFS returns a memFS holding the files, keyed by name.
+func newFS(files map[string]string) memFS {
+	fs := memFS{}
+	for name, text := range files {
+		data := []byte(text)
+		fs[name] = &data
+	}
+	return fs
+}
+
+// loadAndRun loads the file name from fs and runs it, returning what it
+// printed.
+func loadAndRun(t *testing.T, fs memFS, name string) string {
+	t.Helper()
+	bob := NewInterpreter()
+	out := new(bytes.Buffer)
+	bob.Output = out
+	bob.Files = fs
+	if err := bob.Load(name); err != nil {
+		t.Fatalf("Load(%q): %v", name, err)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return out.String()
+}
+
+func TestInclude(t *testing.T) {
+	fs := newFS(map[string]string{
+		"MAIN.BAS": "10 REM $INCLUDE: \"LIB.BAS\"\n" +
+			"20 GOSUB 1000\n" +
+			"30 PRINT \"MAIN\"\n" +
+			"40 STOP\n",
+		"LIB.BAS": "1000 PRINT \"LIB\"\n" +
+			"1010 REM $include: 'MORE.BAS'\n" +
+			"1020 RETURN\n",
+		"MORE.BAS": "1015 PRINT \"MORE\"\n",
+	})
+	bob := NewInterpreter()
+	out := new(bytes.Buffer)
+	bob.Output = out
+	bob.Files = fs
+	if err := bob.Load("MAIN.BAS"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := bob.Run(); !errors.Is(err, ErrStop) {
+		t.Fatalf("Run: %v, want ErrStop", err)
+	}
+	if want := "LIB\nMORE\nMAIN\n"; out.String() != want {
+		t.Errorf("printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		cycle string
+	}{
+		{"self", map[string]string{
+			"A.BAS": "10 REM $INCLUDE: \"A.BAS\"\n",
+		}, "A.BAS -> A.BAS"},
+		{"indirect", map[string]string{
+			"A.BAS": "10 REM $INCLUDE: \"B.BAS\"\n",
+			"B.BAS": "20 REM $INCLUDE: \"C.BAS\"\n",
+			"C.BAS": "30 REM $INCLUDE: \"A.BAS\"\n",
+		}, "A.BAS -> B.BAS -> C.BAS -> A.BAS"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			bob := NewInterpreter()
+			bob.Files = newFS(tt.files)
+			err := bob.Load("A.BAS")
+			if err == nil || !strings.Contains(err.Error(), "include cycle: "+tt.cycle) {
+				t.Errorf("Load: got %v, want include cycle %s", err, tt.cycle)
+			}
+		})
+	}
+}
+
+func TestIncludeTwice(t *testing.T) {
+	// including the same file twice is not a cycle
+	fs := newFS(map[string]string{
+		"MAIN.BAS": "10 REM $INCLUDE: \"LIB.BAS\"\n" +
+			"20 REM $INCLUDE: \"LIB.BAS\"\n" +
+			"30 PRINT \"DONE\"\n",
+		"LIB.BAS": "25 PRINT \"LIB\"\n",
+	})
+	if got, want := loadAndRun(t, fs, "MAIN.BAS"), "LIB\nDONE\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestIncludeName(t *testing.T) {
+	tests := []struct {
+		line string
+		name string
+		ok   bool
+	}{
+		{`10 REM $INCLUDE: "LIB.BAS"`, "LIB.BAS", true},
+		{`10 REM $INCLUDE: 'LIB.BAS'`, "LIB.BAS", true},
+		{`10 REM $include:"LIB.BAS"`, "LIB.BAS", true},
+		{`10 REM $INCLUDE: LIB.BAS`, "", false},
+		{`10 REM $INCLUDE: "LIB.BAS'`, "", false},
+		{`10 REM INCLUDE: "LIB.BAS"`, "", false},
+		{`10 PRINT "$INCLUDE: 'LIB.BAS'"`, "", false},
+		{`10`, "", false},
+	}
+	for _, tt := range tests {
+		name, ok := includeName(tt.line)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("includeName(%q) = %q, %v, want %q, %v", tt.line, name, ok, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestIncludeMissing(t *testing.T) {
+	bob := NewInterpreter()
+	bob.Files = newFS(map[string]string{
+		"MAIN.BAS": "10 REM $INCLUDE: \"NOPE.BAS\"\n",
+	})
+	if err := bob.Load("MAIN.BAS"); err == nil {
+		t.Error("expected an error for a missing include")
+	}
+}
+
+func TestLineContinuation(t *testing.T) {
+	fs := newFS(map[string]string{
+		"MAIN.BAS": "10 DATA 1, _\n" +
+			"   2, _\n" +
+			"   3\n" +
+			"20 FOR I=1 TO 3\n" +
+			"21 READ A\n" +
+			"22 PRINT A; \" \";\n" +
+			"23 NEXT I\n" +
+			"30 PRINT \"A\"; _  \n" +
+			"\"B\"\n",
+	})
+	if got, want := loadAndRun(t, fs, "MAIN.BAS"), "1 2 3 AB\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestLineScanner(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"10 A\n20 B\n", []string{"10 A", "20 B"}},
+		{"10 A _\nB _\nC\n20 D", []string{"10 A B C", "20 D"}},
+		// trailing spaces after the _ are ignored
+		{"10 A_ \t\nB\n", []string{"10 AB"}},
+		// a continued last line is still a line
+		{"10 A _\n", []string{"10 A "}},
+		{"10 A$=\"_\"\n", []string{"10 A$=\"_\""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		ls := newLineScanner(strings.NewReader(tt.src))
+		for ls.Scan() {
+			got = append(got, ls.Text())
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("%q: scanned %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestLineContinuationLineNumber(t *testing.T) {
+	bob := NewInterpreter()
+	if err := bob.LoadReader("X", strings.NewReader("10 PRINT _\n1\n20 PRINT 2\n")); err != nil {
+		t.Fatalf("LoadReader: %v", err)
+	}
+	// the joined line keeps the number of the first
+	if got, want := listing(t, bob), "10 PRINT 1\n20 PRINT 2\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+}
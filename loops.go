@@ -0,0 +1,289 @@
+package gdbasic
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// loopFrame is an active FOR loop.
+type loopFrame struct {
+	varName string
+	limit   Value
+	step    Value
+	// line is the line number of the FOR.
+	line int
+}
+
+// defaultMaxCallDepth is the MaxCallDepth of a new Interpreter.
+const defaultMaxCallDepth = 1000
+
+// GosubInstruction calls the subroutine at the line number, RETURN continues
+// from the line after the GOSUB.
+type GosubInstruction int
+
+func (gi GosubInstruction) Execute(intp *Interpreter) error {
+	line, err := intp.executingLine()
+	if err != nil {
+		return err
+	}
+	if intp.MaxCallDepth > 0 && len(intp.returnStack) >= intp.MaxCallDepth {
+		return fmt.Errorf("out of memory: GOSUB nested more than %d deep", intp.MaxCallDepth)
+	}
+	intp.returnStack = append(intp.returnStack, line)
+	return intp.SetPC(int(gi))
+}
+
+// executingLine returns the line number of the instruction being executed,
+// for instructions that need to come back to it. They cannot be used in
+// direct mode as there is no line to come back to.
+func (bob *Interpreter) executingLine() (int, error) {
+	if bob.direct {
+		return 0, fmt.Errorf("illegal direct")
+	}
+	return bob.intructionIndex[bob.pc-1], nil
+}
+
+func (gi GosubInstruction) String() string {
+	return fmt.Sprintf("GOSUB %v", int(gi))
+}
+
+func (gi GosubInstruction) LineRefs() []int { return []int{int(gi)} }
+
+func (gi GosubInstruction) RenumberRefs(lines map[int]int) Instructioner {
+	return GosubInstruction(lines[int(gi)])
+}
+
+func NewGosubInstruction(_ int, remainder string) (GosubInstruction, error) {
+	i64, err := strconv.ParseInt(remainder, 10, 32)
+	if err != nil {
+		return GosubInstruction(0), fmt.Errorf("gosub has a bad line number `%s`: %v", remainder, err)
+	}
+	return GosubInstruction(i64), nil
+}
+
+// ReturnInstruction returns from the last GOSUB.
+type ReturnInstruction struct{}
+
+func (ReturnInstruction) Execute(intp *Interpreter) error {
+	if len(intp.returnStack) == 0 {
+		return fmt.Errorf("RETURN without GOSUB")
+	}
+	line := intp.returnStack[len(intp.returnStack)-1]
+	intp.returnStack = intp.returnStack[:len(intp.returnStack)-1]
+	return intp.setPCAfter(line)
+}
+
+func (ReturnInstruction) String() string { return "RETURN" }
+
+// setPCAfter moves execution to the line after the given line number.
+func (bob *Interpreter) setPCAfter(linenumber int) error {
+	if err := bob.SetPC(linenumber); err != nil {
+		return err
+	}
+	bob.pc++
+	return nil
+}
+
+// ForInstruction starts a loop, setting the variable to Start and running
+// the lines up to the matching NEXT until the variable passes Limit. If the
+// variable starts past Limit the loop body is skipped entirely.
+type ForInstruction struct {
+	VarName string
+	Start   Expression
+	Limit   Expression
+	// Step is nil when the step is the default of 1.
+	Step Expression
+}
+
+func (fi ForInstruction) Execute(intp *Interpreter) error {
+	start, err := fi.Start.Eval(intp)
+	if err != nil {
+		return err
+	}
+	limit, err := fi.Limit.Eval(intp)
+	if err != nil {
+		return err
+	}
+	step := Value{Int: 1}
+	if fi.Step != nil {
+		if step, err = fi.Step.Eval(intp); err != nil {
+			return err
+		}
+	}
+	if start.IsStr || limit.IsStr || step.IsStr {
+		return fmt.Errorf("type mismatch: %s", fi)
+	}
+	line, err := intp.executingLine()
+	if err != nil {
+		return err
+	}
+	if err := intp.checkWritable(fi.VarName); err != nil {
+		return err
+	}
+	if err := intp.setVariable(fi.VarName, start); err != nil {
+		return err
+	}
+
+	// Starting the loop again discards the old one and any inside it.
+	for i, frame := range intp.loopStack {
+		if frame.varName == fi.VarName {
+			intp.loopStack = intp.loopStack[:i]
+			break
+		}
+	}
+	frame := loopFrame{
+		varName: fi.VarName,
+		limit:   limit,
+		step:    step,
+		line:    line,
+	}
+	done, err := frame.finished(start)
+	if err != nil {
+		return err
+	}
+	if done {
+		return intp.skipLoop(fi.VarName)
+	}
+	intp.loopStack = append(intp.loopStack, frame)
+	return nil
+}
+
+// skipLoop moves execution past the NEXT that matches the FOR of varName.
+func (bob *Interpreter) skipLoop(varName string) error {
+	depth := 0
+	for i := bob.pc; i < len(bob.intructionIndex); i++ {
+		switch ins := unlabeled(bob.Instructions[bob.intructionIndex[i]]).(type) {
+		case *ForInstruction:
+			depth++
+		case *NextInstruction:
+			if depth == 0 && (ins.VarName == "" || ins.VarName == varName) {
+				bob.pc = i + 1
+				return nil
+			}
+			depth--
+		}
+	}
+	return fmt.Errorf("FOR without NEXT: %s", varName)
+}
+
+// finished reports whether val has gone past the limit of the loop.
+func (frame loopFrame) finished(val Value) (bool, error) {
+	cmp, err := val.Compare(frame.limit)
+	if err != nil {
+		return false, err
+	}
+	if frame.step.float() < 0 {
+		return cmp < 0, nil
+	}
+	return cmp > 0, nil
+}
+
+func (fi ForInstruction) String() string {
+	if fi.Step != nil {
+		return fmt.Sprintf("FOR %s=%s TO %s STEP %s", fi.VarName, fi.Start, fi.Limit, fi.Step)
+	}
+	return fmt.Sprintf("FOR %s=%s TO %s", fi.VarName, fi.Start, fi.Limit)
+}
+
+func NewForInstruction(_ int, remainder string) (*ForInstruction, error) {
+	// FOR I=1 TO 10 STEP 2
+	name, rest, ok := strings.Cut(remainder, "=")
+	if !ok {
+		return nil, fmt.Errorf("invalid for statement")
+	}
+	fi := &ForInstruction{VarName: varName(name)}
+	if fi.VarName == "" || isStringVar(fi.VarName) {
+		return nil, fmt.Errorf("for has a bad variable `%s`", fi.VarName)
+	}
+	start, rest, ok := cutKeyword(rest, "TO")
+	if !ok {
+		return nil, fmt.Errorf("for is missing TO")
+	}
+	limit, step, hasStep := cutKeyword(rest, "STEP")
+	var err error
+	col := len(name) + len("=")
+	if fi.Start, err = ParseExpression(start); err != nil {
+		return nil, shiftParseError(err, col)
+	}
+	col += len(start) + len("TO")
+	if fi.Limit, err = ParseExpression(limit); err != nil {
+		return nil, shiftParseError(err, col)
+	}
+	if hasStep {
+		col += len(limit) + len("STEP")
+		if fi.Step, err = ParseExpression(step); err != nil {
+			return nil, shiftParseError(err, col)
+		}
+	}
+	return fi, nil
+}
+
+// NextInstruction ends the body of a FOR loop. Without a variable it ends
+// the innermost loop.
+type NextInstruction struct {
+	VarName string
+}
+
+func (ni NextInstruction) Execute(intp *Interpreter) error {
+	idx := len(intp.loopStack) - 1
+	if ni.VarName != "" {
+		for idx >= 0 && intp.loopStack[idx].varName != ni.VarName {
+			idx--
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("NEXT without FOR")
+	}
+	// Any loops inside this one have been left.
+	intp.loopStack = intp.loopStack[:idx+1]
+	frame := intp.loopStack[idx]
+
+	val, err := BinaryExpression{Op: "+", Left: Reference(frame.varName), Right: frame.step}.Eval(intp)
+	if err != nil {
+		return err
+	}
+	if err := intp.setVariable(frame.varName, val); err != nil {
+		return err
+	}
+	done, err := frame.finished(val)
+	if err != nil {
+		return err
+	}
+	if done {
+		intp.loopStack = intp.loopStack[:idx]
+		return nil
+	}
+	return intp.setPCAfter(frame.line)
+}
+
+func (ni NextInstruction) String() string {
+	if ni.VarName == "" {
+		return "NEXT"
+	}
+	return "NEXT " + ni.VarName
+}
+
+func NewNextInstruction(_ int, remainder string) (*NextInstruction, error) {
+	return &NextInstruction{VarName: varName(remainder)}, nil
+}
+
+// StackDump writes the return stack of the active GOSUBs and the loop stack
+// of the active FOR loops to w, innermost first.
+func (bob *Interpreter) StackDump(w io.Writer) error {
+	var buf strings.Builder
+	buf.WriteString("Return stack:\n")
+	for i := len(bob.returnStack) - 1; i >= 0; i-- {
+		fmt.Fprintf(&buf, "  GOSUB at line %d\n", bob.returnStack[i])
+	}
+	buf.WriteString("Loop stack:\n")
+	for i := len(bob.loopStack) - 1; i >= 0; i-- {
+		frame := bob.loopStack[i]
+		val, _ := bob.GetVar(frame.varName)
+		fmt.Fprintf(&buf, "  FOR %s at line %d: %s=%s limit %s step %s\n",
+			frame.varName, frame.line, frame.varName, val, frame.limit, frame.step)
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
@@ -0,0 +1,97 @@
+package gdbasic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStackDump(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`10 GOSUB 100`,
+		`20 STOP`,
+		`100 GOSUB 200`,
+		`110 RETURN`,
+		`200 FOR I=1 TO 3 STEP 2`,
+		`210 FOR J=5 TO 1 STEP -1`,
+		`220 PRINT I; J`,
+		`230 NEXT J`,
+		`240 NEXT I`,
+		`250 RETURN`,
+	)
+	var dump strings.Builder
+	if err := bob.StackDump(&dump); err != nil {
+		t.Fatalf("StackDump: %v", err)
+	}
+	if got, want := dump.String(), "Return stack:\nLoop stack:\n"; got != want {
+		t.Errorf("before running got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// step until the first time line 220 is reached
+	for bob.CurrentLine() != 220 {
+		if done, err := bob.Step(); done || err != nil {
+			t.Fatalf("Step() = %v, %v before reaching line 220", done, err)
+		}
+	}
+	dump.Reset()
+	if err := bob.StackDump(&dump); err != nil {
+		t.Fatalf("StackDump: %v", err)
+	}
+	want := strings.Join([]string{
+		"Return stack:",
+		"  GOSUB at line 100",
+		"  GOSUB at line 10",
+		"Loop stack:",
+		"  FOR J at line 210: J=5 limit 1 step -1",
+		"  FOR I at line 200: I=1 limit 3 step 2",
+	}, "\n") + "\n"
+	if got := dump.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	for _, depth := range []int{1, 5, 50, defaultMaxCallDepth} {
+		bob, _ := newTestInterpreter(t,
+			`10 GOSUB 100`,
+			`100 LET N=N+1`,
+			`110 GOSUB 100`,
+		)
+		if bob.MaxCallDepth != defaultMaxCallDepth {
+			t.Errorf("new interpreter has MaxCallDepth %d, want %d", bob.MaxCallDepth, defaultMaxCallDepth)
+		}
+		bob.MaxCallDepth = depth
+		err := bob.Run()
+		if err == nil || !strings.Contains(err.Error(), "out of memory") {
+			t.Errorf("depth %d: Run() = %v, want out of memory", depth, err)
+		}
+		// the limit is hit at exactly the configured depth
+		if n, _ := bob.GetVar("N"); n.Int != depth {
+			t.Errorf("depth %d: recursed %d times", depth, n.Int)
+		}
+		if len(bob.returnStack) != depth {
+			t.Errorf("depth %d: return stack is %d deep", depth, len(bob.returnStack))
+		}
+	}
+}
+
+func TestMaxCallDepthUnlimited(t *testing.T) {
+	// 0 is no limit, and a recursion that stops is not an error
+	for _, max := range []int{0, 2000} {
+		bob, out := newTestInterpreter(t,
+			`10 GOSUB 100`,
+			`20 PRINT N`,
+			`30 GOTO 200`,
+			`100 LET N=N+1`,
+			`110 IF N<2000 THEN GOSUB 100`,
+			`120 RETURN`,
+			`200 REM`,
+		)
+		bob.MaxCallDepth = max
+		if err := bob.Run(); err != nil {
+			t.Fatalf("MaxCallDepth %d: Run: %v", max, err)
+		}
+		if out.String() != "2000\n" {
+			t.Errorf("MaxCallDepth %d: printed %q, want %q", max, out.String(), "2000\n")
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package gdbasic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultMemorySize is the number of bytes of simulated memory PEEK and
+// POKE work on for an interpreter made by NewInterpreter.
+const DefaultMemorySize = 65536
+
+// memoryAddr checks that addr is inside the simulated memory.
+func (bob *Interpreter) memoryAddr(name string, addr int) error {
+	if addr < 0 || addr >= len(bob.memory) {
+		return fmt.Errorf("%s: address %d out of range 0-%d", name, addr, len(bob.memory)-1)
+	}
+	return nil
+}
+
+// memoryRange checks that the n bytes from addr are inside the simulated
+// memory.
+func (bob *Interpreter) memoryRange(name string, addr, n int) error {
+	if addr < 0 || n < 0 || addr+n > len(bob.memory) {
+		return fmt.Errorf("%s: %d bytes at address %d out of range 0-%d", name, n, addr, len(bob.memory)-1)
+	}
+	return nil
+}
+
+// fnPeek returns the byte at an address of the simulated memory.
+func fnPeek(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("PEEK", args, "#"); err != nil {
+		return Value{}, err
+	}
+	addr := args[0].int()
+	if err := intp.memoryAddr("PEEK", addr); err != nil {
+		return Value{}, err
+	}
+	return Value{Int: int(intp.memory[addr])}, nil
+}
+
+// PokeInstruction stores a byte in the simulated memory, only the low 8 bits
+// of the value are kept.
+type PokeInstruction struct {
+	Addr  Expression
+	Value Expression
+}
+
+func (pi PokeInstruction) Execute(intp *Interpreter) error {
+	addr, err := evalInt(intp, pi.Addr)
+	if err != nil {
+		return err
+	}
+	val, err := evalInt(intp, pi.Value)
+	if err != nil {
+		return err
+	}
+	if err := intp.memoryAddr("POKE", addr); err != nil {
+		return err
+	}
+	intp.memory[addr] = byte(val & 0xFF)
+	return nil
+}
+
+func (pi PokeInstruction) String() string {
+	return fmt.Sprintf("POKE %s, %s", pi.Addr, pi.Value)
+}
+
+func NewPokeInstruction(_ int, remainder string) (*PokeInstruction, error) {
+	// POKE 1024, 65
+	args, err := ParseExpressionList(remainder)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("poke needs an address and a value `%s`", strings.TrimSpace(remainder))
+	}
+	return &PokeInstruction{Addr: args[0], Value: args[1]}, nil
+}
+
+// memBlock is a run of bytes in the simulated memory.
+type memBlock struct {
+	addr, size int
+}
+
+// storeString writes s, the text of the string variable name, into string
+// space and its descriptor to the variable's VARPTR address. A string
+// keeps its place while the new text still fits, otherwise it is given a
+// new block below the others; the old one is not reused.
+func (bob *Interpreter) storeString(name, s string) error {
+	if len(s) > 255 {
+		return fmt.Errorf("string too long for memory: %s", name)
+	}
+	if bob.stringTop == 0 {
+		// the descriptor only has 2 bytes for the address
+		bob.stringTop = len(bob.memory)
+		if bob.stringTop > 0x10000 {
+			bob.stringTop = 0x10000
+		}
+	}
+	desc := bob.varAddr(name)
+	block, ok := bob.stringBlocks[name]
+	if !ok || len(s) > block.size {
+		block = memBlock{addr: bob.stringTop - len(s), size: len(s)}
+		if block.addr < bob.nextVarAddr {
+			return fmt.Errorf("out of string space storing %s", name)
+		}
+		if bob.stringBlocks == nil {
+			bob.stringBlocks = map[string]memBlock{}
+		}
+		bob.stringBlocks[name] = block
+		bob.stringTop = block.addr
+	}
+	if desc+3 > bob.stringTop {
+		return fmt.Errorf("out of memory storing %s", name)
+	}
+	copy(bob.memory[block.addr:], s)
+	bob.memory[desc] = byte(len(s))
+	bob.memory[desc+1] = byte(block.addr)
+	bob.memory[desc+2] = byte(block.addr >> 8)
+	return nil
+}
+
+// BsaveInstruction writes Length bytes of the simulated memory, starting at
+// Addr, to a file opened using the interpreter's Files. The file holds just
+// the bytes.
+type BsaveInstruction struct {
+	Name   Expression
+	Addr   Expression
+	Length Expression
+}
+
+func (bi BsaveInstruction) Execute(intp *Interpreter) error {
+	name, err := evalString(intp, bi.Name)
+	if err != nil {
+		return err
+	}
+	addr, err := evalInt(intp, bi.Addr)
+	if err != nil {
+		return err
+	}
+	length, err := evalInt(intp, bi.Length)
+	if err != nil {
+		return err
+	}
+	if err := intp.memoryRange("BSAVE", addr, length); err != nil {
+		return err
+	}
+	file, err := intp.Files.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(intp.memory[addr : addr+length]); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (bi BsaveInstruction) String() string {
+	return fmt.Sprintf("BSAVE %s, %s, %s", bi.Name, bi.Addr, bi.Length)
+}
+
+func NewBsaveInstruction(_ int, remainder string) (*BsaveInstruction, error) {
+	// BSAVE "SCREEN.BIN", 1024, 256
+	args, err := ParseExpressionList(remainder)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 3 {
+		return nil, fmt.Errorf("bsave needs a file name, address and length `%s`", strings.TrimSpace(remainder))
+	}
+	return &BsaveInstruction{Name: args[0], Addr: args[1], Length: args[2]}, nil
+}
+
+// BloadInstruction reads the whole of a file, as written by BSAVE, into the
+// simulated memory starting at Addr. Nothing is loaded if it does not fit.
+type BloadInstruction struct {
+	Name Expression
+	Addr Expression
+}
+
+func (bi BloadInstruction) Execute(intp *Interpreter) error {
+	name, err := evalString(intp, bi.Name)
+	if err != nil {
+		return err
+	}
+	addr, err := evalInt(intp, bi.Addr)
+	if err != nil {
+		return err
+	}
+	if err := intp.memoryAddr("BLOAD", addr); err != nil {
+		return err
+	}
+	file, err := intp.Files.OpenFile(name, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	if err := intp.memoryRange("BLOAD", addr, len(data)); err != nil {
+		return err
+	}
+	copy(intp.memory[addr:], data)
+	return nil
+}
+
+func (bi BloadInstruction) String() string {
+	return fmt.Sprintf("BLOAD %s, %s", bi.Name, bi.Addr)
+}
+
+func NewBloadInstruction(_ int, remainder string) (*BloadInstruction, error) {
+	// BLOAD "SCREEN.BIN", 1024
+	args, err := ParseExpressionList(remainder)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("bload needs a file name and address `%s`", strings.TrimSpace(remainder))
+	}
+	return &BloadInstruction{Name: args[0], Addr: args[1]}, nil
+}
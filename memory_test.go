@@ -0,0 +1,251 @@
+package gdbasic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBsaveBload(t *testing.T) {
+	fs := memFS{}
+	runWithFiles(t, fs,
+		`10 POKE 100, 1`,
+		`20 POKE 101, 2`,
+		`30 POKE 102, 255`,
+		`40 BSAVE "MEM.BIN", 100, 3`,
+	)
+	if want := "\x01\x02\xff"; fs.contents("MEM.BIN") != want {
+		t.Errorf("file holds %q, want %q", fs.contents("MEM.BIN"), want)
+	}
+
+	// a fresh interpreter has zeroed memory
+	got := runWithFiles(t, fs,
+		`10 PRINT PEEK(200); " "; PEEK(201); " "; PEEK(202)`,
+		`20 BLOAD "MEM.BIN", 200`,
+		`30 PRINT PEEK(200); " "; PEEK(201); " "; PEEK(202); " "; PEEK(203)`,
+	)
+	if want := "0 0 0\n1 2 255 0\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestBsaveBloadErrors(t *testing.T) {
+	data := []byte("ABCD")
+	for _, program := range [][]string{
+		{`10 BSAVE "F", -1, 2`},
+		{`10 BSAVE "F", 65535, 2`},
+		{`10 BSAVE "F", 0, -1`},
+		{`10 BLOAD "F4", 65536`},
+		{`10 BLOAD "F4", 65533`},
+		{`10 BLOAD "MISSING", 0`},
+	} {
+		bob, _ := newTestInterpreter(t, program...)
+		bob.Files = memFS{"F4": &data}
+		if err := bob.Run(); err == nil {
+			t.Errorf("%s: expected an error", strings.Join(program, " / "))
+		}
+	}
+}
+
+func TestBsaveBloadParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		err  bool
+	}{
+		{line: `BSAVE "A.BIN", 10, 20`, want: `BSAVE "A.BIN", 10, 20`},
+		{line: `BLOAD "A.BIN", 10`, want: `BLOAD "A.BIN", 10`},
+		{line: `BSAVE "A.BIN", 10`, err: true},
+		{line: `BLOAD "A.BIN"`, err: true},
+	}
+	for _, tc := range tests {
+		ins, err := parseInstruction(10, tc.line)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.line, err)
+			continue
+		}
+		if got := ins.String(); got != tc.want {
+			t.Errorf("%s: listed as %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestStringMemory(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A$="HELLO"`,
+		`20 LET D=VARPTR(A$)`,
+		`30 LET P=PEEK(D+1)+256*PEEK(D+2)`,
+		`40 PRINT PEEK(D); " "; CHR$(PEEK(P)); CHR$(PEEK(P+4))`,
+		`50 LET A$="HI"`,
+		`60 PRINT PEEK(D); " "; PEEK(D+1)+256*PEEK(D+2)=P; " "; CHR$(PEEK(P+1))`,
+		`70 LET A$="HELLO WORLD"`,
+		`80 LET Q=PEEK(D+1)+256*PEEK(D+2)`,
+		`90 PRINT PEEK(D); " "; Q<>P; " "; CHR$(PEEK(Q+6))`,
+	)
+	bob.StringMemory = true
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// the shorter string reuses its block, the longer one is moved
+	if want := "5 HO\n2 -1 I\n11 -1 W\n"; out.String() != want {
+		t.Errorf("printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestStringMemoryOff(t *testing.T) {
+	got := runProgram(t,
+		`10 LET A$="HELLO"`,
+		`20 LET D=VARPTR(A$)`,
+		`30 PRINT PEEK(D); " "; PEEK(D+1); " "; PEEK(D+2)`,
+	)
+	if want := "0 0 0\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestStringMemoryStatements(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"READ", []string{`10 DATA "ABC"`, `20 READ A$`}, "ABC"},
+		{"MID$", []string{`10 LET A$="ABC"`, `20 MID$(A$, 2, 1)="X"`}, "AXC"},
+		{"SWAP", []string{`10 LET A$="ABC"`, `20 LET B$="Z"`, `30 SWAP A$, B$`}, "Z"},
+		{"CONST", []string{`10 CONST A$="ABC"`}, "ABC"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tt.lines...)
+			bob.StringMemory = true
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			desc := bob.varAddrs["A$"]
+			n := int(bob.memory[desc])
+			addr := int(bob.memory[desc+1]) | int(bob.memory[desc+2])<<8
+			if got := string(bob.memory[addr : addr+n]); got != tt.want {
+				t.Errorf("memory holds %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringMemoryErrors(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 LET A$=STRING$(256, "A")`)
+	bob.StringMemory = true
+	if err := bob.Run(); err == nil {
+		t.Error("expected an error for a string over 255 characters")
+	}
+
+	bob = NewInterpreterWithMemory(varBaseAddr + 8)
+	bob.Output = new(bytes.Buffer)
+	bob.StringMemory = true
+	for _, line := range []string{`10 LET A$="ABC"`, `20 LET B$="DEFGH"`} {
+		if err := bob.Interpret(line); err != nil {
+			t.Fatalf("Interpret(%q): %v", line, err)
+		}
+	}
+	err := bob.Run()
+	if err == nil || !strings.Contains(err.Error(), "out of") {
+		t.Errorf("Run: got %v, want out of string space", err)
+	}
+}
+
+func TestStringMemoryClear(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A$="HELLO"`,
+		`20 CLEAR`,
+		`30 LET B$="AB"`,
+		`40 LET D=VARPTR(B$)`,
+		`50 PRINT PEEK(D); " "; PEEK(D+1)+256*PEEK(D+2)`,
+	)
+	bob.StringMemory = true
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// after CLEAR string space starts again from the top
+	if want := "2 65534\n"; out.String() != want {
+		t.Errorf("printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestPeekPoke(t *testing.T) {
+	tests := []struct {
+		poke string
+		want string
+	}{
+		{`65`, "65"},
+		{`0`, "0"},
+		{`255`, "255"},
+		// only the low 8 bits are kept
+		{`256`, "0"},
+		{`256+65`, "65"},
+		{`-1`, "255"},
+		{`&H1234`, "52"},
+		{`65.7`, "65"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.poke, func(t *testing.T) {
+			got := runProgram(t,
+				`10 POKE 1024, `+tt.poke,
+				`20 PRINT PEEK(1024); " "; PEEK(1025)`,
+			)
+			if want := tt.want + " 0\n"; got != want {
+				t.Errorf("printed %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPeekPokeRange(t *testing.T) {
+	for _, stmt := range []string{
+		`POKE -1, 0`,
+		`POKE 16, 0`,
+		`PRINT PEEK(-1)`,
+		`PRINT PEEK(16)`,
+		`PRINT PEEK("A")`,
+		`POKE "A", 1`,
+	} {
+		bob := NewInterpreterWithMemory(16)
+		bob.Output = new(bytes.Buffer)
+		if err := bob.Interpret(`10 ` + stmt); err != nil {
+			t.Fatalf("Interpret(%q): %v", stmt, err)
+		}
+		if err := bob.Run(); err == nil {
+			t.Errorf("%s: expected an error", stmt)
+		}
+	}
+	// the last byte is in range
+	bob := NewInterpreterWithMemory(16)
+	out := new(bytes.Buffer)
+	bob.Output = out
+	for _, line := range []string{`10 POKE 15, 7`, `20 PRINT PEEK(15)`} {
+		if err := bob.Interpret(line); err != nil {
+			t.Fatalf("Interpret(%q): %v", line, err)
+		}
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.String() != "7\n" {
+		t.Errorf("printed %q, want %q", out.String(), "7\n")
+	}
+}
+
+func TestPokeParse(t *testing.T) {
+	for _, line := range []string{`10 POKE 1`, `10 POKE 1, 2, 3`, `10 POKE`} {
+		if err := NewInterpreter().Interpret(line); err == nil {
+			t.Errorf("%s: expected a parse error", line)
+		}
+	}
+	bob, _ := newTestInterpreter(t, `10 POKE 1024,A+1`)
+	if got, want := listing(t, bob), "10 POKE 1024, A+1\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+}
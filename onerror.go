@@ -0,0 +1,157 @@
+package gdbasic
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrDivisionByZero is returned, wrapped, when a number is divided by zero
+// and DivZeroZero is not set.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Error codes returned by ERR, as in GW-BASIC.
+const (
+	errCodeDivisionByZero = 11
+	// errCodeUnknown is the code of any other error.
+	errCodeUnknown = 255
+)
+
+// errorCode returns the code ERR gives for err.
+func errorCode(err error) int {
+	if errors.Is(err, ErrDivisionByZero) {
+		return errCodeDivisionByZero
+	}
+	return errCodeUnknown
+}
+
+// trapError sends execution to the ON ERROR GOTO handler, if there is one,
+// for the error returned by the instruction on line. It reports whether the
+// error was trapped. An error in the handler itself is not trapped, and
+// neither are STOP and running out of output.
+func (bob *Interpreter) trapError(line int, err error) bool {
+	if bob.errHandler == 0 || bob.inErrHandler || bob.direct ||
+		errors.Is(err, ErrStop) || errors.Is(err, ErrOutputLimit) {
+		return false
+	}
+	if bob.SetPC(bob.errHandler) != nil {
+		return false
+	}
+	bob.errCode, bob.errLine = errorCode(err), line
+	bob.inErrHandler = true
+	return true
+}
+
+// fnErr returns the code of the last error trapped by ON ERROR GOTO.
+func fnErr(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("ERR", args, ""); err != nil {
+		return Value{}, err
+	}
+	return Value{Int: intp.errCode}, nil
+}
+
+// fnErl returns the line of the last error trapped by ON ERROR GOTO.
+func fnErl(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("ERL", args, ""); err != nil {
+		return Value{}, err
+	}
+	return Value{Int: intp.errLine}, nil
+}
+
+// OnErrorInstruction sets the line that errors are sent to, ON ERROR GOTO 0
+// turns trapping off so errors stop the program again.
+type OnErrorInstruction int
+
+func (oe OnErrorInstruction) Execute(intp *Interpreter) error {
+	intp.errHandler = int(oe)
+	return nil
+}
+
+func (oe OnErrorInstruction) String() string {
+	return fmt.Sprintf("ON ERROR GOTO %v", int(oe))
+}
+
+func (oe OnErrorInstruction) LineRefs() []int {
+	if oe == 0 {
+		return nil
+	}
+	return []int{int(oe)}
+}
+
+func (oe OnErrorInstruction) RenumberRefs(lines map[int]int) Instructioner {
+	return OnErrorInstruction(lines[int(oe)])
+}
+
+func NewOnErrorInstruction(_ int, remainder string) (OnErrorInstruction, error) {
+	// ON ERROR GOTO 1000
+	fields := strings.Fields(remainder)
+	if len(fields) != 3 || fields[0] != "ERROR" || fields[1] != "GOTO" {
+		return 0, fmt.Errorf("unknown ON statement `%s`", remainder)
+	}
+	i64, err := strconv.ParseInt(fields[2], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("on error has a bad line number `%s`: %v", fields[2], err)
+	}
+	return OnErrorInstruction(i64), nil
+}
+
+// ResumeInstruction ends an error handler. It carries on from the line that
+// had the error, the line after it if Next is set, or Line if it is not 0.
+type ResumeInstruction struct {
+	Next bool
+	Line int
+}
+
+func (ri ResumeInstruction) Execute(intp *Interpreter) error {
+	if !intp.inErrHandler {
+		return fmt.Errorf("RESUME without error")
+	}
+	intp.inErrHandler = false
+	switch {
+	case ri.Next:
+		return intp.setPCAfter(intp.errLine)
+	case ri.Line != 0:
+		return intp.SetPC(ri.Line)
+	}
+	return intp.SetPC(intp.errLine)
+}
+
+func (ri ResumeInstruction) String() string {
+	switch {
+	case ri.Next:
+		return "RESUME NEXT"
+	case ri.Line != 0:
+		return fmt.Sprintf("RESUME %v", ri.Line)
+	}
+	return "RESUME"
+}
+
+func (ri ResumeInstruction) LineRefs() []int {
+	if ri.Line == 0 {
+		return nil
+	}
+	return []int{ri.Line}
+}
+
+func (ri ResumeInstruction) RenumberRefs(lines map[int]int) Instructioner {
+	if ri.Line != 0 {
+		ri.Line = lines[ri.Line]
+	}
+	return ri
+}
+
+func NewResumeInstruction(_ int, remainder string) (ResumeInstruction, error) {
+	// RESUME, RESUME NEXT or RESUME 100
+	switch remainder {
+	case "":
+		return ResumeInstruction{}, nil
+	case "NEXT":
+		return ResumeInstruction{Next: true}, nil
+	}
+	i64, err := strconv.ParseInt(remainder, 10, 32)
+	if err != nil {
+		return ResumeInstruction{}, fmt.Errorf("resume has a bad line number `%s`: %v", remainder, err)
+	}
+	return ResumeInstruction{Line: int(i64)}, nil
+}
@@ -0,0 +1,126 @@
+package gdbasic
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{"resume next", []string{
+			`10 ON ERROR GOTO 100`,
+			`20 PRINT 1/0`,
+			`30 PRINT "AFTER"`,
+			`40 GOTO 200`,
+			`100 PRINT "ERR"; ERR; " LINE"; ERL`,
+			`110 RESUME NEXT`,
+			`200 REM`,
+		}, "ERR11 LINE20\nAFTER\n"},
+		{"resume retries the line", []string{
+			`10 ON ERROR GOTO 100`,
+			`20 LET D=0`,
+			`30 PRINT 10/D`,
+			`40 GOTO 200`,
+			`100 LET D=2`,
+			`110 RESUME`,
+			`200 REM`,
+		}, "5\n"},
+		{"resume line", []string{
+			`10 ON ERROR GOTO 100`,
+			`20 PRINT 7\0`,
+			`30 PRINT "SKIPPED"`,
+			`40 PRINT "RESUMED"`,
+			`50 GOTO 200`,
+			`100 RESUME 40`,
+			`200 REM`,
+		}, "RESUMED\n"},
+		{"trapped twice", []string{
+			`10 ON ERROR GOTO 100`,
+			`20 PRINT 1 MOD 0`,
+			`30 PRINT 1/0`,
+			`40 GOTO 200`,
+			`100 PRINT ERL`,
+			`110 RESUME NEXT`,
+			`200 REM`,
+		}, "20\n30\n"},
+		{"other errors", []string{
+			`10 ON ERROR GOTO 100`,
+			`20 PRINT "A"+1`,
+			`30 GOTO 200`,
+			`100 PRINT ERR`,
+			`110 RESUME NEXT`,
+			`200 REM`,
+		}, "255\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runProgram(t, tt.program...); got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnErrorNotTrapped(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+	}{
+		{"no handler", []string{`10 PRINT 1/0`}},
+		{"turned off", []string{
+			`10 ON ERROR GOTO 100`,
+			`20 ON ERROR GOTO 0`,
+			`30 PRINT 1/0`,
+			`100 RESUME NEXT`,
+		}},
+		{"error in the handler", []string{
+			`10 ON ERROR GOTO 100`,
+			`20 PRINT 1/0`,
+			`100 PRINT 2/0`,
+			`110 RESUME NEXT`,
+		}},
+		{"resume without error", []string{`10 RESUME`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bob, _ := newTestInterpreter(t, tt.program...)
+			if err := bob.Run(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	// STOP is not an error to trap
+	bob, _ := newTestInterpreter(t, `10 ON ERROR GOTO 100`, `20 STOP`, `100 RESUME NEXT`)
+	if err := bob.Run(); !errors.Is(err, ErrStop) {
+		t.Errorf("Run: %v, want ErrStop", err)
+	}
+}
+
+func TestOnErrorParse(t *testing.T) {
+	for _, line := range []string{
+		`10 ON ERROR GOTO 100`,
+		`10 ON ERROR GOTO 0`,
+		`10 RESUME`,
+		`10 RESUME NEXT`,
+		`10 RESUME 20`,
+	} {
+		bob, _ := newTestInterpreter(t, line)
+		if got := listing(t, bob); got != line+"\n" {
+			t.Errorf("listing is %q, want %q", got, line+"\n")
+		}
+	}
+	for _, line := range []string{
+		`10 ON ERROR 100`,
+		`10 ON ERROR GOTO X`,
+		`10 RESUME LATER`,
+	} {
+		if err := NewInterpreter().Interpret(line); err == nil {
+			t.Errorf("%s: expected a parse error", line)
+		}
+	}
+}
@@ -0,0 +1,594 @@
+package gdbasic
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PrintInstruction writes its items to the interpreter's Output.
+type PrintInstruction struct {
+	strings   []IntrepreterStringer
+	NoNewline bool
+}
+
+// PrintExpression is an expression used as a PRINT item. Text is the item
+// as it was written, which is how it is listed.
+type PrintExpression struct {
+	Expression
+	Text string
+}
+
+func (pe PrintExpression) String() string {
+	if pe.Text != "" {
+		return pe.Text
+	}
+	return pe.Expression.String()
+}
+
+func (pe PrintExpression) IntrepString(intp *Interpreter) (string, error) {
+	val, err := pe.Eval(intp)
+	if err != nil {
+		return "", err
+	}
+	return val.IntrepString(intp)
+}
+
+// output returns the writer the program's output goes to, Output limited
+// to MaxOutputBytes.
+func (bob *Interpreter) output() io.Writer {
+	if bob.MaxOutputBytes <= 0 {
+		return bob.Output
+	}
+	return limitWriter{bob}
+}
+
+// limitWriter writes to the interpreter's Output until MaxOutputBytes have
+// been written, then returns ErrOutputLimit.
+type limitWriter struct {
+	bob *Interpreter
+}
+
+func (lw limitWriter) Write(p []byte) (int, error) {
+	room := lw.bob.MaxOutputBytes - lw.bob.outputBytes
+	if room < 0 {
+		room = 0
+	}
+	limited := len(p) > room
+	if limited {
+		p = p[:room]
+	}
+	n, err := lw.bob.Output.Write(p)
+	lw.bob.outputBytes += n
+	if err == nil && limited {
+		err = ErrOutputLimit
+	}
+	return n, err
+}
+
+// cursor tracks the column an output device is at, so TAB knows how far to pad.
+// If width is set, lines longer than width are wrapped.
+type cursor struct {
+	column int
+	width  int
+}
+
+func (c *cursor) write(w io.Writer, s string) error {
+	if c.width > 0 {
+		s = c.wrap(s)
+	}
+	if _, err := io.WriteString(w, s); err != nil {
+		return err
+	}
+	if idx := strings.LastIndexByte(s, '\n'); idx != -1 {
+		c.column = len(s) - idx - 1
+	} else {
+		c.column += len(s)
+	}
+	return nil
+}
+
+// overflows reports whether s, printed at the cursor, would go past the width
+// when it would not if started at the beginning of a line.
+func (c *cursor) overflows(s string) bool {
+	if c.width <= 0 || c.column == 0 {
+		return false
+	}
+	line, _, _ := strings.Cut(s, "\n")
+	return c.column+len(line) > c.width && len(line) <= c.width
+}
+
+// wrap inserts newlines into s so that no line goes past the width.
+func (c *cursor) wrap(s string) string {
+	var buf strings.Builder
+	column := c.column
+	for _, r := range s {
+		if r == '\n' {
+			column = 0
+		} else {
+			if column >= c.width {
+				buf.WriteByte('\n')
+				column = 0
+			}
+			column++
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// Tab moves the cursor to the given column.
+type Tab int
+
+func (tab Tab) String() string { return fmt.Sprintf("TAB(%d)", int(tab)) }
+
+func (tab Tab) IntrepString(intp *Interpreter) (string, error) {
+	return tab.padding(intp.screen.column, intp.TabNewline), nil
+}
+
+// padding returns the string needed to move from column to the tab column. If
+// the cursor is already past the tab column, either nothing is returned or,
+// when newline is set, a newline followed by the padding to the tab column.
+func (tab Tab) padding(column int, newline bool) string {
+	n := int(tab)
+	if column <= n {
+		return strings.Repeat(" ", n-column)
+	}
+	if !newline {
+		return ""
+	}
+	return "\n" + strings.Repeat(" ", n)
+}
+
+// defaultZoneWidth is the width of the print zones a comma moves between.
+const defaultZoneWidth = 14
+
+// ZoneWidth returns the width of the print zones.
+func (bob *Interpreter) ZoneWidth() int { return bob.zoneWidth }
+
+// SetZoneWidth sets the width of the print zones that a comma between PRINT
+// items moves the cursor to the start of, it must be at least 1.
+func (bob *Interpreter) SetZoneWidth(width int) error {
+	if width <= 0 {
+		return fmt.Errorf("zone width must be positive, not %d", width)
+	}
+	bob.zoneWidth = width
+	return nil
+}
+
+// Zone moves the cursor to the start of the next print zone, it is the
+// comma between PRINT items.
+type Zone struct{}
+
+func (Zone) String() string { return "," }
+
+func (Zone) IntrepString(intp *Interpreter) (string, error) {
+	return zonePadding(intp.screen.column, intp.zoneWidth), nil
+}
+
+// zonePadding returns the spaces needed to move from column to the start of
+// the next zone of the given width.
+func zonePadding(column, width int) string {
+	return strings.Repeat(" ", width-column%width)
+}
+
+func (pi PrintInstruction) Execute(inter *Interpreter) error {
+	return pi.print(inter, inter.output(), &inter.screen)
+}
+
+// print writes the items to w, using c to keep track of the column.
+func (pi PrintInstruction) print(inter *Interpreter, w io.Writer, c *cursor) error {
+	for _, val := range pi.strings {
+		var (
+			s   string
+			err error
+		)
+		if tab, ok := val.(Tab); ok {
+			s = tab.padding(c.column, inter.TabNewline)
+		} else if _, ok := val.(Zone); ok {
+			s = zonePadding(c.column, inter.zoneWidth)
+		} else if s, err = val.IntrepString(inter); err != nil {
+			return err
+		} else if c.overflows(s) {
+			// As in BASIC, an item that will not fit on the rest of the
+			// line is moved to the next one rather than being split.
+			if err = c.write(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err = c.write(w, s); err != nil {
+			return err
+		}
+	}
+	if !pi.NoNewline {
+		return c.write(w, "\n")
+	}
+	return nil
+}
+func (pi PrintInstruction) String() string {
+
+	var buf strings.Builder
+	semicolon := ""
+	if pi.NoNewline {
+		semicolon = ";"
+		if n := len(pi.strings); n > 0 {
+			if _, ok := pi.strings[n-1].(Zone); ok {
+				// the trailing comma is already there
+				semicolon = ""
+			}
+		}
+	}
+
+	for i := range pi.strings {
+		strv := pi.strings[i].String()
+		_, zone := pi.strings[i].(Zone)
+		afterZone := false
+		if i > 0 {
+			_, afterZone = pi.strings[i-1].(Zone)
+		}
+		if i == 0 && strv[0] != '"' {
+			buf.WriteRune(' ')
+		} else if i != 0 && !zone && !afterZone {
+			buf.WriteRune(';')
+		}
+		buf.WriteString(pi.strings[i].String())
+	}
+	return fmt.Sprintf("PRINT%s%s", buf.String(), semicolon)
+}
+
+// splitPrintItems splits the items of a PRINT on the semicolons and commas
+// that are outside of quotes and parentheses. seps holds the separator that
+// follows each item but the last.
+func splitPrintItems(s string) (items []string, seps string) {
+	inQuote, depth, start := false, 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (c == ';' || c == ','):
+			items = append(items, s[start:i])
+			seps += string(c)
+			start = i + 1
+		}
+	}
+	return append(items, s[start:]), seps
+}
+
+func NewPrintInstruction(line int, remainder string) (pi *PrintInstruction, err error) {
+	remainder = strings.TrimSpace(remainder)
+	if len(remainder) == 0 {
+		// just a newline
+		return new(PrintInstruction), nil
+	}
+	pi = new(PrintInstruction)
+	// a trailing ; or , leaves the cursor where it is, or at the next
+	// zone, for the next PRINT
+	last := remainder[len(remainder)-1]
+	pi.NoNewline = last == ';' || last == ','
+
+	var output strings.Builder
+
+	parameters, seps := splitPrintItems(remainder)
+	next := 0
+	for i := range parameters {
+		if i > 0 && seps[i-1] == ',' {
+			if output.Len() != 0 {
+				pi.strings = append(pi.strings, strValue(output.String()))
+				output.Reset()
+			}
+			pi.strings = append(pi.strings, Zone{})
+		}
+		// col is where the parameter starts in remainder
+		col := next + len(parameters[i]) - len(strings.TrimLeft(parameters[i], " \t"))
+		next += len(parameters[i]) + len(";")
+		parameters[i] = strings.TrimSpace(parameters[i])
+		if len(parameters[i]) == 0 {
+			continue
+		}
+		switch {
+		case IsString(parameters[i]):
+			output.WriteString(getString(parameters[i]))
+		case strings.HasPrefix(parameters[i], "TAB("):
+			// We have a tab.
+			idx := strings.Index(parameters[i], ")")
+			if idx == -1 {
+				return nil, parseErrorf(col+len(parameters[i]), "incomplete tab command, missing `)`")
+			}
+			num, err := strconv.Atoi(parameters[i][4:idx])
+			if err != nil {
+				return nil, parseErrorf(col+4, "incomplete tab command, bad column `%s`", parameters[i][4:idx])
+			}
+			if output.Len() != 0 {
+				pi.strings = append(pi.strings, strValue(output.String()))
+				output.Reset()
+			}
+			pi.strings = append(pi.strings, Tab(num))
+		default:
+			// anything else is an expression, most often just a variable
+			expr, err := ParseExpression(parameters[i])
+			if err != nil {
+				return nil, shiftParseError(err, col)
+			}
+			if output.Len() != 0 {
+				pi.strings = append(pi.strings, strValue(output.String()))
+				output.Reset()
+			}
+			pi.strings = append(pi.strings, PrintExpression{Expression: expr, Text: parameters[i]})
+		}
+	}
+
+	if output.Len() != 0 {
+		pi.strings = append(pi.strings, strValue(output.String()))
+	}
+
+	return pi, err
+}
+
+// PrintUsingInstruction prints its items formatted by a mask such as
+// `###.##`, see formatUsing for the format of the mask.
+type PrintUsingInstruction struct {
+	Mask      Expression
+	Items     []Expression
+	NoNewline bool
+}
+
+func (pui PrintUsingInstruction) Execute(inter *Interpreter) error {
+	mask, err := pui.Mask.Eval(inter)
+	if err != nil {
+		return err
+	}
+	if !mask.IsStr {
+		return fmt.Errorf("print using: mask must be a string")
+	}
+	values := make([]Value, len(pui.Items))
+	for i := range pui.Items {
+		if values[i], err = pui.Items[i].Eval(inter); err != nil {
+			return err
+		}
+	}
+	s, err := formatUsing(mask.Str, values)
+	if err != nil {
+		return err
+	}
+	if !pui.NoNewline {
+		s += "\n"
+	}
+	return inter.screen.write(inter.output(), s)
+}
+
+func (pui PrintUsingInstruction) String() string {
+	items := make([]string, len(pui.Items))
+	for i := range pui.Items {
+		items[i] = pui.Items[i].String()
+	}
+	semicolon := ""
+	if pui.NoNewline {
+		semicolon = ";"
+	}
+	return fmt.Sprintf("PRINT USING %s;%s%s", pui.Mask, strings.Join(items, ";"), semicolon)
+}
+
+func NewPrintUsingInstruction(_ int, remainder string) (*PrintUsingInstruction, error) {
+	// PRINT USING "###.##"; A; B
+	remainder = strings.TrimSpace(remainder)
+	parameters, _ := splitPrintItems(remainder)
+	if len(parameters) < 2 {
+		return nil, fmt.Errorf("print using: expected a mask and values")
+	}
+	mask, err := ParseExpression(parameters[0])
+	if err != nil {
+		return nil, fmt.Errorf("print using: %v", err)
+	}
+	pui := &PrintUsingInstruction{Mask: mask}
+	last := remainder[len(remainder)-1]
+	pui.NoNewline = last == ';' || last == ','
+	for _, param := range parameters[1:] {
+		if strings.TrimSpace(param) == "" {
+			continue
+		}
+		item, err := ParseExpression(param)
+		if err != nil {
+			return nil, fmt.Errorf("print using: %v", err)
+		}
+		pui.Items = append(pui.Items, item)
+	}
+	if len(pui.Items) == 0 {
+		return nil, fmt.Errorf("print using: expected values to format")
+	}
+	return pui, nil
+}
+
+// LprintInstruction is a PRINT that goes to the printer instead of the screen.
+type LprintInstruction struct {
+	PrintInstruction
+}
+
+func (lpi LprintInstruction) Execute(inter *Interpreter) error {
+	return lpi.print(inter, inter.PrinterOutput, &inter.printer)
+}
+
+func (lpi LprintInstruction) String() string {
+	return "L" + lpi.PrintInstruction.String()
+}
+
+func NewLprintInstruction(line int, remainder string) (*LprintInstruction, error) {
+	pi, err := NewPrintInstruction(line, remainder)
+	if err != nil {
+		return nil, err
+	}
+	if pi == nil {
+		pi = new(PrintInstruction)
+	}
+	return &LprintInstruction{PrintInstruction: *pi}, nil
+}
+
+// WriteInstruction prints values separated by commas, with strings in double
+// quotes and numbers without the spaces PRINT puts around them.
+type WriteInstruction struct {
+	Items []Expression
+}
+
+func (wi WriteInstruction) Execute(intp *Interpreter) error {
+	items := make([]string, len(wi.Items))
+	for i, item := range wi.Items {
+		val, err := item.Eval(intp)
+		if err != nil {
+			return err
+		}
+		if val.IsStr {
+			items[i] = `"` + val.Str + `"`
+		} else {
+			items[i] = val.String()
+		}
+	}
+	return intp.screen.write(intp.output(), strings.Join(items, ",")+"\n")
+}
+
+func (wi WriteInstruction) String() string {
+	items := make([]string, len(wi.Items))
+	for i, item := range wi.Items {
+		items[i] = item.String()
+	}
+	return strings.TrimSpace("WRITE " + strings.Join(items, ", "))
+}
+
+func NewWriteInstruction(_ int, remainder string) (*WriteInstruction, error) {
+	// WRITE A, "hi", 2
+	if remainder == "" {
+		return new(WriteInstruction), nil
+	}
+	items, err := ParseExpressionList(remainder)
+	if err != nil {
+		return nil, fmt.Errorf("write: %v", err)
+	}
+	return &WriteInstruction{Items: items}, nil
+}
+
+// WidthInstruction sets the line width of the screen, or of the printer for
+// WIDTH LPRINT. A width of 0 turns off wrapping.
+type WidthInstruction struct {
+	Printer bool
+	Width   int
+}
+
+func (wi WidthInstruction) Execute(intp *Interpreter) error {
+	if wi.Printer {
+		intp.printer.width = wi.Width
+		return nil
+	}
+	intp.screen.width = wi.Width
+	return nil
+}
+
+func (wi WidthInstruction) String() string {
+	if wi.Printer {
+		return fmt.Sprintf("WIDTH LPRINT %d", wi.Width)
+	}
+	return fmt.Sprintf("WIDTH %d", wi.Width)
+}
+
+func NewWidthInstruction(_ int, remainder string) (*WidthInstruction, error) {
+	// WIDTH 40 or WIDTH LPRINT 132
+	wi := new(WidthInstruction)
+	if strings.HasPrefix(remainder, "LPRINT") {
+		wi.Printer = true
+		remainder = strings.TrimSpace(remainder[len("LPRINT"):])
+	}
+	width, err := strconv.Atoi(remainder)
+	if err != nil || width < 0 || width > 255 {
+		return nil, fmt.Errorf("width has a bad width `%s`", remainder)
+	}
+	wi.Width = width
+	return wi, nil
+}
+
+// ColorInstruction sets the foreground, and optionally the background, colour
+// of the screen using ANSI escape sequences. Colours 0-15 are the standard
+// 16 colour palette, 0-7 normal and 8-15 bright. Nothing is written when the
+// interpreter's NoEscapes is set.
+type ColorInstruction struct {
+	Foreground int
+	// Background is -1 when it is not being changed.
+	Background int
+}
+
+func (ci ColorInstruction) Execute(intp *Interpreter) error {
+	if intp.NoEscapes {
+		return nil
+	}
+	seq := "\x1b[" + strconv.Itoa(ansiColor(ci.Foreground, 30))
+	if ci.Background >= 0 {
+		seq += ";" + strconv.Itoa(ansiColor(ci.Background, 40))
+	}
+	// The escape sequence takes up no columns so it bypasses the cursor.
+	_, err := io.WriteString(intp.output(), seq+"m")
+	return err
+}
+
+// ansiColor returns the SGR parameter for colour c, where base is 30 for the
+// foreground and 40 for the background.
+func ansiColor(c, base int) int {
+	if c >= 8 {
+		return base + 60 + c - 8
+	}
+	return base + c
+}
+
+func (ci ColorInstruction) String() string {
+	if ci.Background >= 0 {
+		return fmt.Sprintf("COLOR %d, %d", ci.Foreground, ci.Background)
+	}
+	return fmt.Sprintf("COLOR %d", ci.Foreground)
+}
+
+func NewColorInstruction(_ int, remainder string) (*ColorInstruction, error) {
+	// COLOR 14 or COLOR 15, 1
+	ci := &ColorInstruction{Background: -1}
+	parts := strings.Split(remainder, ",")
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("color takes a foreground and background `%s`", remainder)
+	}
+	for i, part := range parts {
+		c, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || c < 0 || c > 15 {
+			return nil, fmt.Errorf("color has a bad colour `%s`", part)
+		}
+		if i == 0 {
+			ci.Foreground = c
+		} else {
+			ci.Background = c
+		}
+	}
+	return ci, nil
+}
+
+// fnPos returns the column the screen cursor is at, counting from 0 as TAB
+// does, so POS(0) after TAB(10) is 10. The argument is ignored.
+func fnPos(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("POS", args, "?"); err != nil {
+		return Value{}, err
+	}
+	return Value{Int: intp.screen.column}, nil
+}
+
+// ClsInstruction clears the screen and moves the cursor to the top left
+// corner. When the interpreter's NoEscapes is set it does nothing, so that
+// output going to a file is not cluttered.
+type ClsInstruction struct{}
+
+func (ClsInstruction) Execute(intp *Interpreter) error {
+	if intp.NoEscapes {
+		return nil
+	}
+	intp.screen.column = 0
+	_, err := io.WriteString(intp.output(), "\x1b[2J\x1b[H")
+	return err
+}
+
+func (ClsInstruction) String() string { return "CLS" }
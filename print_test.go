@@ -0,0 +1,748 @@
+package gdbasic
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintTab(t *testing.T) {
+	tests := []struct {
+		name       string
+		program    []string
+		tabNewline bool
+		want       string
+	}{
+		{
+			name:    "forward",
+			program: []string{`10 PRINT "AB"; TAB(5); "X"`},
+			want:    "AB   X\n",
+		},
+		{
+			name:    "at the column",
+			program: []string{`10 PRINT "ABCDE"; TAB(5); "X"`},
+			want:    "ABCDEX\n",
+		},
+		{
+			name:    "behind the cursor does nothing",
+			program: []string{`10 PRINT "ABCDEFGHIJ"; TAB(5); "X"`},
+			want:    "ABCDEFGHIJX\n",
+		},
+		{
+			name:       "behind the cursor with TabNewline",
+			program:    []string{`10 PRINT "ABCDEFGHIJ"; TAB(5); "X"`},
+			tabNewline: true,
+			want:       "ABCDEFGHIJ\n     X\n",
+		},
+		{
+			name:       "forward with TabNewline",
+			program:    []string{`10 PRINT "AB"; TAB(5); "X"`},
+			tabNewline: true,
+			want:       "AB   X\n",
+		},
+		{
+			name: "cursor carries over a trailing semicolon",
+			program: []string{
+				`10 PRINT "AB";`,
+				`20 PRINT TAB(4); "X"`,
+			},
+			want: "AB  X\n",
+		},
+		{
+			name: "cursor is reset by a newline",
+			program: []string{
+				`10 PRINT "ABCDEFGH"`,
+				`20 PRINT TAB(4); "X"`,
+			},
+			want: "ABCDEFGH\n    X\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t, tc.program...)
+			bob.TabNewline = tc.tabNewline
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLprint(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 PRINT "SCREEN";`,
+		`20 LPRINT "AB"; TAB(4); "P"`,
+		`30 PRINT TAB(8); "S"`,
+		`40 LPRINT`,
+	)
+	var printer bytes.Buffer
+	bob.PrinterOutput = &printer
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// the screen and the printer each have their own column for TAB
+	if got, want := out.String(), "SCREEN  S\n"; got != want {
+		t.Errorf("screen got %q, want %q", got, want)
+	}
+	if got, want := printer.String(), "AB  P\n\n"; got != want {
+		t.Errorf("printer got %q, want %q", got, want)
+	}
+}
+
+func TestWidth(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 WIDTH 4`,
+		`20 WIDTH LPRINT 6`,
+		`30 PRINT "ABCDEFGHIJ"`,
+		`40 LPRINT "ABCDEFGHIJ"`,
+		`50 WIDTH 0`,
+		`60 PRINT "ABCDEFGHIJ"`,
+	)
+	var printer bytes.Buffer
+	bob.PrinterOutput = &printer
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got, want := out.String(), "ABCD\nEFGH\nIJ\nABCDEFGHIJ\n"; got != want {
+		t.Errorf("screen got %q, want %q", got, want)
+	}
+	if got, want := printer.String(), "ABCDEF\nGHIJ\n"; got != want {
+		t.Errorf("printer got %q, want %q", got, want)
+	}
+}
+
+func TestWidthParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		err  bool
+	}{
+		{line: `WIDTH 40`, want: `WIDTH 40`},
+		{line: `WIDTH LPRINT 132`, want: `WIDTH LPRINT 132`},
+		{line: `WIDTH 0`, want: `WIDTH 0`},
+		{line: `WIDTH 256`, err: true},
+		{line: `WIDTH -1`, err: true},
+		{line: `WIDTH LPRINT`, err: true},
+	}
+	for _, tc := range tests {
+		ins, err := parseInstruction(10, tc.line)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.line, err)
+			continue
+		}
+		if got := ins.String(); got != tc.want {
+			t.Errorf("%s: listed as %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestPrintQuotedSeparators(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`10 PRINT "A;B"`, "A;B\n"},
+		{`10 PRINT "A;B"; "C,D"`, "A;BC,D\n"},
+		{`10 PRINT "X:Y"`, "X:Y\n"},
+		{`10 PRINT ";"; ";"`, ";;\n"},
+		{`10 PRINT "SAY ""HI; THERE"""`, "SAY \"HI; THERE\"\n"},
+		{`10 PRINT "A;";`, "A;"},
+	}
+	for _, tc := range tests {
+		if got := runProgram(t, tc.line); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestSplitPrintItems(t *testing.T) {
+	tests := []struct {
+		in    string
+		items []string
+		seps  string
+	}{
+		{`A`, []string{`A`}, ``},
+		{`"A;B";C`, []string{`"A;B"`, `C`}, `;`},
+		{`"A,B",C;D`, []string{`"A,B"`, `C`, `D`}, `,;`},
+		{`MID$(A$,1,2);B`, []string{`MID$(A$,1,2)`, `B`}, `;`},
+		{`"(";A`, []string{`"("`, `A`}, `;`},
+		{`A;`, []string{`A`, ``}, `;`},
+	}
+	for _, tc := range tests {
+		items, seps := splitPrintItems(tc.in)
+		if !reflect.DeepEqual(items, tc.items) || seps != tc.seps {
+			t.Errorf("splitPrintItems(%q) = %q, %q, want %q, %q", tc.in, items, seps, tc.items, tc.seps)
+		}
+	}
+}
+
+func TestPrintUsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{
+			name:    "numbers",
+			program: []string{`10 PRINT USING "###.##"; 3.14159; -2`},
+			want:    "  3.14 -2.00\n",
+		},
+		{
+			name:    "comma between items",
+			program: []string{`10 PRINT USING "## "; 1, 2`},
+			want:    " 1  2 \n",
+		},
+		{
+			name:    "trailing semicolon",
+			program: []string{`10 PRINT USING "##"; 5;`, `20 PRINT "X"`},
+			want:    " 5X\n",
+		},
+		{
+			name:    "mask in a variable",
+			program: []string{`10 LET M$="[&]"`, `20 PRINT USING M$; "HI"`},
+			want:    "[HI]\n",
+		},
+		{
+			name:    "function with several arguments",
+			program: []string{`10 PRINT USING "&"; MID$("123", 1, 2)`},
+			want:    "12\n",
+		},
+		{
+			name:    "nested calls",
+			program: []string{`10 PRINT USING "##"; VAL(MID$("123", 1, 2))`},
+			want:    "12\n",
+		},
+		{
+			name:    "user function with several arguments",
+			program: []string{`10 DEF FNA(X, Y)=X+Y`, `20 PRINT USING "###"; FNA(1, 2); FNA(3, 4)`},
+			want:    "  3  7\n",
+		},
+		{
+			name:    "separators in the mask",
+			program: []string{`10 PRINT USING "#;#:#"; 1; 2; 3`},
+			want:    "1;2:3\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runProgram(t, tc.program...); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrintUsingErrors(t *testing.T) {
+	for _, line := range []string{
+		`PRINT USING "##"`,
+		`PRINT USING "##";`,
+		`PRINT USING "##"; (1`,
+	} {
+		if _, err := parseInstruction(10, line); err == nil {
+			t.Errorf("%s: expected an error", line)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		name      string
+		program   []string
+		noEscapes bool
+		want      string
+	}{
+		{
+			name:    "foreground",
+			program: []string{`10 COLOR 2`, `20 PRINT "HI"`},
+			want:    "\x1b[32mHI\n",
+		},
+		{
+			name:    "bright foreground and background",
+			program: []string{`10 COLOR 14, 1`, `20 PRINT "HI"`},
+			want:    "\x1b[96;41mHI\n",
+		},
+		{
+			name:    "palette ends",
+			program: []string{`10 COLOR 0, 15`},
+			want:    "\x1b[30;107m",
+		},
+		{
+			name:      "no escapes",
+			program:   []string{`10 COLOR 14, 1`, `20 PRINT "HI"`},
+			noEscapes: true,
+			want:      "HI\n",
+		},
+		{
+			name:    "escapes take up no columns",
+			program: []string{`10 PRINT "AB";`, `20 COLOR 1`, `30 PRINT TAB(4); "X"`},
+			want:    "AB\x1b[31m  X\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t, tc.program...)
+			bob.NoEscapes = tc.noEscapes
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestColorParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		err  bool
+	}{
+		{line: `COLOR 7`, want: `COLOR 7`},
+		{line: `COLOR 15,0`, want: `COLOR 15, 0`},
+		{line: `COLOR 16`, err: true},
+		{line: `COLOR -1`, err: true},
+		{line: `COLOR 1, 2, 3`, err: true},
+		{line: `COLOR RED`, err: true},
+	}
+	for _, tc := range tests {
+		ins, err := parseInstruction(10, tc.line)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.line, err)
+			continue
+		}
+		if got := ins.String(); got != tc.want {
+			t.Errorf("%s: listed as %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestCls(t *testing.T) {
+	tests := []struct {
+		noEscapes bool
+		want      string
+	}{
+		// clearing homes the cursor, so TAB starts again from column 0
+		{false, "AB\x1b[2J\x1b[H  X\n"},
+		{true, "ABX\n"},
+	}
+	for _, tc := range tests {
+		bob, out := newTestInterpreter(t,
+			`10 PRINT "AB";`,
+			`20 CLS`,
+			`30 PRINT TAB(2); "X"`,
+		)
+		bob.NoEscapes = tc.noEscapes
+		if err := bob.Run(); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if got := out.String(); got != tc.want {
+			t.Errorf("NoEscapes %v: got %q, want %q", tc.noEscapes, got, tc.want)
+		}
+	}
+}
+
+func TestPrintWrapsItems(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "item moved to the next line",
+			line: `20 PRINT A$; B$; C$`,
+			want: "ABCDEFGH\nIJK\n",
+		},
+		{
+			name: "item that just fits",
+			line: `20 PRINT A$; B$; "XY"`,
+			want: "ABCDEFGHXY\n",
+		},
+		{
+			name: "numbers",
+			line: `20 PRINT 1234; 5678; 123`,
+			want: "12345678\n123\n",
+		},
+		{
+			// it would not fit at the start of a line either, so it is
+			// split where it is rather than moved
+			name: "item wider than the line",
+			line: `20 PRINT "AB"; L$`,
+			want: "ABCDEFGHIJ\nKLMN\n",
+		},
+		{
+			name: "first item wider than the line",
+			line: `20 PRINT "AB" + L$; C$`,
+			want: "ABCDEFGHIJ\nKLMNIJK\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := runProgram(t,
+				`10 WIDTH 10`,
+				`11 LET A$="ABCD"`,
+				`12 LET B$="EFGH"`,
+				`13 LET C$="IJK"`,
+				`14 LET L$="CDEFGHIJKLMN"`,
+				tc.line,
+			)
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPos(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{
+			name:    "start of a line",
+			program: []string{`10 PRINT POS(0)`},
+			want:    "0\n",
+		},
+		{
+			name:    "after text",
+			program: []string{`10 PRINT "HELLO";`, `20 LET P=POS(0)`, `30 PRINT`, `40 PRINT P`},
+			want:    "HELLO\n5\n",
+		},
+		{
+			name:    "after TAB",
+			program: []string{`10 PRINT TAB(10);`, `20 LET P=POS(0)`, `30 PRINT`, `40 PRINT P`},
+			want:    "          \n10\n",
+		},
+		{
+			name:    "after a zone",
+			program: []string{`10 PRINT "A",;`, `20 LET P=POS(0)`, `30 PRINT`, `40 PRINT P`},
+			want:    "A             \n14\n",
+		},
+		{
+			name:    "reset by a newline",
+			program: []string{`10 PRINT "HELLO"`, `20 PRINT POS(0)`},
+			want:    "HELLO\n0\n",
+		},
+		{
+			name:    "within a PRINT",
+			program: []string{`10 PRINT "ABC"; POS(0)`},
+			want:    "ABC3\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runProgram(t, tc.program...); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`10 WRITE 1, "hi", 2`, "1,\"hi\",2\n"},
+		{`10 WRITE`, "\n"},
+		{`10 WRITE -1.5, ""`, "-1.5,\"\"\n"},
+		{`10 WRITE "A,B", 3`, "\"A,B\",3\n"},
+		{`10 WRITE 1+2, "X"+"Y"`, "3,\"XY\"\n"},
+	}
+	for _, tc := range tests {
+		if got := runProgram(t, tc.line); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+	got := runProgram(t, `10 LET A=7`, `20 LET B$="SEVEN"`, `30 WRITE A, B$`)
+	if want := "7,\"SEVEN\"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPrintShorthand(t *testing.T) {
+	tests := []struct {
+		line   string
+		output string
+		listed string
+	}{
+		{`?X`, "5\n", `PRINT X`},
+		{`? "hi"`, "hi\n", `PRINT"hi"`},
+		{`?"hi"`, "hi\n", `PRINT"hi"`},
+		{`?`, "\n", `PRINT`},
+		{`? X; "!"`, "5!\n", `PRINT X;"!"`},
+	}
+	for _, tc := range tests {
+		bob, out := newTestInterpreter(t, `10 LET X=5`, `20 `+tc.line)
+		if err := bob.Run(); err != nil {
+			t.Fatalf("%s: Run: %v", tc.line, err)
+		}
+		if got := out.String(); got != tc.output {
+			t.Errorf("%s: printed %q, want %q", tc.line, got, tc.output)
+		}
+		// listings always spell it out
+		if got := bob.Instructions[20].String(); got != tc.listed {
+			t.Errorf("%s: listed as %q, want %q", tc.line, got, tc.listed)
+		}
+	}
+}
+
+func TestZoneWidth(t *testing.T) {
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{14, "A             B             C\n12345         X\n"},
+		{10, "A         B         C\n12345     X\n"},
+		{4, "A   B   C\n12345   X\n"},
+		// a comma always moves on to the next zone
+		{1, "A B C\n12345 X\n"},
+	}
+	for _, tt := range tests {
+		bob, out := newTestInterpreter(t,
+			`10 PRINT "A", "B", "C"`,
+			`20 LET N=12345`,
+			`30 PRINT N, "X"`,
+		)
+		if err := bob.SetZoneWidth(tt.width); err != nil {
+			t.Fatalf("SetZoneWidth(%d): %v", tt.width, err)
+		}
+		if got := bob.ZoneWidth(); got != tt.width {
+			t.Errorf("ZoneWidth() = %d, want %d", got, tt.width)
+		}
+		if err := bob.Run(); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("width %d: printed %q, want %q", tt.width, out.String(), tt.want)
+		}
+	}
+}
+
+func TestZoneWidthInvalid(t *testing.T) {
+	bob := NewInterpreter()
+	if got := bob.ZoneWidth(); got != 14 {
+		t.Errorf("default ZoneWidth() = %d, want 14", got)
+	}
+	for _, width := range []int{0, -1, -14} {
+		if err := bob.SetZoneWidth(width); err == nil {
+			t.Errorf("SetZoneWidth(%d): expected an error", width)
+		}
+	}
+	// a rejected width leaves the old one
+	if got := bob.ZoneWidth(); got != 14 {
+		t.Errorf("ZoneWidth() = %d after errors, want 14", got)
+	}
+	if err := bob.SetZoneWidth(8); err != nil {
+		t.Fatal(err)
+	}
+	if got := bob.Clone().ZoneWidth(); got != 8 {
+		t.Errorf("clone has ZoneWidth() = %d, want 8", got)
+	}
+}
+
+func TestPrintTrailingSeparator(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{"semicolon", []string{`10 PRINT "A";`, `20 PRINT "B"`}, "AB\n"},
+		{"comma", []string{`10 PRINT "A",`, `20 PRINT "B"`}, "A             B\n"},
+		{"comma then semicolon", []string{`10 PRINT "A",`, `20 PRINT "B";`, `30 PRINT "C"`}, "A             BC\n"},
+		{"semicolon then comma", []string{`10 PRINT "A";`, `20 PRINT "BC",`, `30 PRINT "D"`}, "ABC           D\n"},
+		{"two commas", []string{`10 PRINT "A",`, `20 PRINT "B",`, `30 PRINT "C"`}, "A             B             C\n"},
+		{"just a comma", []string{`10 PRINT ,`, `20 PRINT "A"`}, "              A\n"},
+		{"just a semicolon", []string{`10 PRINT ;`, `20 PRINT "A"`}, "A\n"},
+		{"leading comma", []string{`10 PRINT , "A"`}, "              A\n"},
+		{"leading semicolon", []string{`10 PRINT ; "A"`}, "A\n"},
+		{"double comma", []string{`10 PRINT "A",, "B"`}, "A                           B\n"},
+		{"newline", []string{`10 PRINT "A"`, `20 PRINT "B"`}, "A\nB\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runProgram(t, tt.program...); got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintTrailingSeparatorColumn(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 PRINT "AB",`)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := bob.screen.column; got != 14 {
+		t.Errorf("column is %d after a trailing comma, want 14", got)
+	}
+	bob, _ = newTestInterpreter(t, `10 PRINT "AB";`)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := bob.screen.column; got != 2 {
+		t.Errorf("column is %d after a trailing semicolon, want 2", got)
+	}
+	for _, line := range []string{`10 PRINT"A",`, `10 PRINT"A";`, `10 PRINT ,`} {
+		bob, _ := newTestInterpreter(t, line)
+		if got := listing(t, bob); got != line+"\n" {
+			t.Errorf("listing is %q, want %q", got, line+"\n")
+		}
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		max   int
+		times int
+		limit bool
+	}{
+		// each PRINT writes "AB\n", 3 bytes
+		{"under", 10, 3, false},
+		{"exactly at the limit", 9, 3, false},
+		{"one byte over", 8, 3, true},
+		{"tight loop", 10, 1000, true},
+		{"no limit", 0, 1000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t,
+				`10 FOR I=1 TO N`,
+				`20 PRINT "AB"`,
+				`30 NEXT I`,
+			)
+			bob.MaxOutputBytes = tt.max
+			bob.SetVar("N", Value{Int: tt.times})
+			err := bob.Run()
+			if tt.limit {
+				if !errors.Is(err, ErrOutputLimit) {
+					t.Fatalf("Run() = %v, want ErrOutputLimit", err)
+				}
+				// what fits is written, nothing more
+				if out.Len() != tt.max {
+					t.Errorf("wrote %d bytes, want %d", out.Len(), tt.max)
+				}
+				if want := strings.Repeat("AB\n", tt.times)[:tt.max]; out.String() != want {
+					t.Errorf("wrote %q, want %q", out.String(), want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if want := strings.Repeat("AB\n", tt.times); out.String() != want {
+				t.Errorf("wrote %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestMaxOutputBytesStatements(t *testing.T) {
+	for _, stmt := range []string{
+		`PRINT "ABCDEFGHIJ"`,
+		`WRITE "ABCDEFGHIJ"`,
+		`PRINT USING "##########"; 1`,
+		`PRINT SPACE$(10)`,
+	} {
+		bob, out := newTestInterpreter(t, `10 `+stmt)
+		bob.MaxOutputBytes = 5
+		if err := bob.Run(); !errors.Is(err, ErrOutputLimit) {
+			t.Errorf("%s: Run() = %v, want ErrOutputLimit", stmt, err)
+		}
+		if out.Len() != 5 {
+			t.Errorf("%s: wrote %d bytes, want 5", stmt, out.Len())
+		}
+	}
+
+	// ON ERROR can't trap the limit and carry on printing
+	bob, out := newTestInterpreter(t,
+		`10 ON ERROR GOTO 100`,
+		`20 PRINT "AB"`,
+		`30 GOTO 20`,
+		`100 RESUME NEXT`,
+	)
+	bob.MaxOutputBytes = 10
+	if err := bob.RunWithLimit(1000); !errors.Is(err, ErrOutputLimit) {
+		t.Errorf("with ON ERROR: Run() = %v, want ErrOutputLimit", err)
+	}
+	if out.Len() != 10 {
+		t.Errorf("with ON ERROR: wrote %d bytes, want 10", out.Len())
+	}
+
+	// the count starts again each run
+	bob, out = newTestInterpreter(t, `10 PRINT "ABCD"`)
+	bob.MaxOutputBytes = 5
+	for i := 0; i < 3; i++ {
+		if err := bob.Run(); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+	if out.String() != "ABCD\nABCD\nABCD\n" {
+		t.Errorf("wrote %q over three runs", out.String())
+	}
+}
+
+func TestPrintExpressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		want    string
+	}{
+		{"lower case names", []string{`10 LET a=5`, `20 PRINT a; a+1`}, "56\n"},
+		{"mixed case", []string{`10 LET Ab=2`, `20 PRINT aB*AB`}, "4\n"},
+		{"bare variable", []string{`10 LET A=5`, `20 PRINT A`}, "5\n"},
+		{"sum", []string{`10 LET A=5`, `20 PRINT A+1`}, "6\n"},
+		{"comparison", []string{`10 LET A=5`, `20 LET B=3`, `30 PRINT A>B; " "; (A<B)`}, "-1 0\n"},
+		{"string", []string{`10 LET n$="bob"`, `20 PRINT N$ + "!"; n$`}, "bob!bob\n"},
+		{"array", []string{`10 DIM a(2)`, `20 LET a(1)=7`, `30 PRINT A(1); a(1)+1`}, "78\n"},
+		{"for", []string{`10 FOR i=1 TO 2`, `20 PRINT I;`, `30 NEXT i`, `40 PRINT`}, "12\n"},
+		{"read", []string{`10 DATA 4`, `20 READ x`, `30 PRINT X*2`}, "8\n"},
+		{"swap", []string{`10 LET a=1`, `20 LET B=2`, `30 SWAP a, b`, `40 PRINT A; B`}, "21\n"},
+		{"const", []string{`10 CONST k=3`, `20 PRINT K+k`}, "6\n"},
+		{"def fn", []string{`10 DEF fnsq(x)=X*x`, `20 PRINT FNSQ(3); fnsq(4)`}, "916\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runProgram(t, tt.program...); got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintExpressionListing(t *testing.T) {
+	// items are listed as they were written
+	for _, line := range []string{
+		`10 PRINT A + 1`,
+		`10 PRINT a;a+1`,
+		`10 PRINT (A > B),"X";-A`,
+	} {
+		bob, _ := newTestInterpreter(t, line)
+		if got := listing(t, bob); got != line+"\n" {
+			t.Errorf("listing is %q, want %q", got, line+"\n")
+		}
+	}
+	bob, _ := newTestInterpreter(t, `10 LET a=1`)
+	if got, want := listing(t, bob), "10 LET A=1\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+}
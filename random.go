@@ -0,0 +1,94 @@
+package gdbasic
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Seed reseeds the random number generator used by RND. Without a call to
+// Seed a program gets the same sequence of numbers every time it is run, as
+// it would in GW-BASIC without RANDOMIZE.
+func (bob *Interpreter) Seed(seed int64) {
+	bob.rngSrc = &countingSource{Source: rand.NewSource(seed), seed: seed}
+	bob.rng = rand.New(bob.rngSrc)
+}
+
+// countingSource is a rand.Source that counts the numbers drawn from it, so
+// that Restore can put the generator back to where a Snapshot was taken.
+type countingSource struct {
+	rand.Source
+	seed  int64
+	draws uint64
+}
+
+func (cs *countingSource) Int63() int64 {
+	cs.draws++
+	return cs.Source.Int63()
+}
+
+// FixedRandom makes RND return the values in seq, in order and starting over
+// at the end, instead of using the random number generator. The values are
+// returned as they are whatever arguments RND is called with, so a program
+// can be given exactly the rolls a test needs. FixedRandom(nil) goes back to
+// the random number generator.
+func (bob *Interpreter) FixedRandom(seq []int) {
+	bob.fixedRnd = append([]int(nil), seq...)
+	bob.nextFixedRnd = 0
+}
+
+func (bob *Interpreter) random() *rand.Rand {
+	if bob.rng == nil {
+		bob.Seed(0)
+	}
+	return bob.rng
+}
+
+// fnRnd returns a random number. With no argument, or a positive one, it is
+// the next number in [0,1). RND(0) repeats the last number and a negative
+// argument reseeds the generator with it first. RND(lo, hi) is an integer
+// between lo and hi inclusive.
+func fnRnd(intp *Interpreter, args []Value) (Value, error) {
+	if len(intp.fixedRnd) > 0 {
+		if len(args) == 1 && args[0].float() == 0 && intp.nextFixedRnd > 0 {
+			return Value{Int: intp.fixedRnd[(intp.nextFixedRnd-1)%len(intp.fixedRnd)]}, nil
+		}
+		val := intp.fixedRnd[intp.nextFixedRnd%len(intp.fixedRnd)]
+		intp.nextFixedRnd++
+		return Value{Int: val}, nil
+	}
+	switch len(args) {
+	case 0:
+	case 1:
+		if err := checkArgs("RND", args, "#"); err != nil {
+			return Value{}, err
+		}
+		switch x := args[0].float(); {
+		case x == 0:
+			return floatValue(intp.lastRnd), nil
+		case x < 0:
+			intp.Seed(int64(x))
+		}
+	default:
+		if err := checkArgs("RND", args, "##"); err != nil {
+			return Value{}, err
+		}
+		bits := intp.IntSize
+		if bits == 0 {
+			bits = 32
+		}
+		// in range bounds can't make hi-lo+1 overflow
+		max := float64(int(1)<<(bits-1) - 1)
+		for _, arg := range args {
+			if f := arg.float(); f > max || f < -max-1 {
+				return Value{}, fmt.Errorf("RND: overflow: %s", arg)
+			}
+		}
+		lo, hi := args[0].int(), args[1].int()
+		if lo > hi {
+			return Value{}, fmt.Errorf("RND: low %d is greater than high %d", lo, hi)
+		}
+		return Value{Int: lo + intp.random().Intn(hi-lo+1)}, nil
+	}
+	intp.lastRnd = intp.random().Float64()
+	return floatValue(intp.lastRnd), nil
+}
@@ -0,0 +1,141 @@
+package gdbasic
+
+import "testing"
+
+func TestRndDice(t *testing.T) {
+	bob := NewInterpreter()
+	bob.Seed(42)
+	seen := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		val, err := eval(t, bob, `INT(RND(1)*6)+1`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// INT gives a float, but a whole one
+		roll := int(val.float())
+		if float64(roll) != val.float() || roll < 1 || roll > 6 {
+			t.Fatalf("rolled %v, want a whole number from 1 to 6", val)
+		}
+		seen[roll]++
+	}
+	if len(seen) != 6 {
+		t.Errorf("rolled %v, want every face", seen)
+	}
+}
+
+func TestRndRange(t *testing.T) {
+	bob := NewInterpreter()
+	bob.Seed(42)
+	seen := map[int]bool{}
+	for i := 0; i < 1000; i++ {
+		val, err := eval(t, bob, `RND(-2, 2)`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if val.IsFloat || val.Int < -2 || val.Int > 2 {
+			t.Fatalf("RND(-2, 2) = %v", val)
+		}
+		seen[val.Int] = true
+	}
+	// both ends are included
+	if !seen[-2] || !seen[2] || len(seen) != 5 {
+		t.Errorf("RND(-2, 2) gave %v, want all of -2 to 2", seen)
+	}
+	testExprs(t, []exprTest{{`RND(5, 5)`, `5`}})
+	testExprErrors(t, `RND(6, 1)`, `RND("A", 1)`)
+}
+
+func TestRndSeed(t *testing.T) {
+	draw := func(bob *Interpreter) []float64 {
+		var vals []float64
+		for i := 0; i < 3; i++ {
+			val, err := eval(t, bob, `RND`)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if val.Float < 0 || val.Float >= 1 {
+				t.Fatalf("RND = %v, want a number in [0,1)", val)
+			}
+			vals = append(vals, val.Float)
+		}
+		return vals
+	}
+	a, b := NewInterpreter(), NewInterpreter()
+	a.Seed(7)
+	b.Seed(7)
+	if x, y := draw(a), draw(b); x[0] != y[0] || x[1] != y[1] || x[2] != y[2] {
+		t.Errorf("the same seed gave %v and %v", x, y)
+	}
+	// RND(0) repeats the last number
+	last, _ := eval(t, a, `RND`)
+	if again, _ := eval(t, a, `RND(0)`); again != last {
+		t.Errorf("RND(0) = %v, want %v", again, last)
+	}
+}
+
+func TestFixedRandom(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 FOR I=1 TO 5`,
+		`20 PRINT RND(1); " ";`,
+		`30 NEXT I`,
+		`40 PRINT RND(0); " "; RND(1, 100); " "; RND`,
+	)
+	bob.FixedRandom([]int{3, 1, 4})
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// the sequence starts over at the end, RND(0) repeats the last value and
+	// the arguments are otherwise ignored
+	if got, want := out.String(), "3 1 4 3 1 1 4 3\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+
+	bob.FixedRandom(nil)
+	val, err := eval(t, bob, `RND`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !val.IsFloat || val.Float < 0 || val.Float >= 1 {
+		t.Errorf("RND after FixedRandom(nil) = %v, want a number in [0,1)", val)
+	}
+}
+
+func TestRndRangeBounds(t *testing.T) {
+	// the widest range there is
+	bob := NewInterpreter()
+	for i := 0; i < 100; i++ {
+		val, err := eval(t, bob, `RND(-2147483648, 2147483647)`)
+		if err != nil {
+			t.Fatalf("RND(-2147483648, 2147483647): %v", err)
+		}
+		if val.IsFloat {
+			t.Fatalf("RND(-2147483648, 2147483647) = %#v, want an integer", val)
+		}
+	}
+	testExprs(t, []exprTest{
+		{`RND(2147483647, 2147483647)`, `2147483647`},
+		{`RND(-2147483648, -2147483648)`, `-2147483648`},
+	})
+	testExprErrors(t,
+		`RND(-5E18, 5E18)`,
+		`RND(0, 5E18)`,
+		`RND(-5E18, 0)`,
+		`RND(0, 2147483648.0)`,
+		`RND(-2147483649.0, 0)`,
+		`RND(1E300, 1E301)`,
+	)
+
+	// the range is smaller with 16 bit integers
+	bob = NewInterpreter()
+	bob.IntSize = 16
+	for _, expr := range []string{`RND(-32768, 32767)`, `RND(0, 32767)`} {
+		if _, err := eval(t, bob, expr); err != nil {
+			t.Errorf("%s with IntSize 16: %v", expr, err)
+		}
+	}
+	for _, expr := range []string{`RND(0, 32768)`, `RND(-32769, 0)`} {
+		if _, err := eval(t, bob, expr); err == nil {
+			t.Errorf("%s with IntSize 16: expected an overflow", expr)
+		}
+	}
+}
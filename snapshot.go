@@ -0,0 +1,120 @@
+package gdbasic
+
+// State is the runtime state of an interpreter, as taken by Snapshot and
+// put back by Restore. It does not include the program. It only holds
+// exported plain data so it can be saved with encoding/json or gob.
+type State struct {
+	// Line is the line that will be executed next, or -1 if the program
+	// has ended.
+	Line      int
+	Variables map[string]Value
+	Arrays    map[string]*Array
+	// ReturnStack holds the lines of the active GOSUBs.
+	ReturnStack []int
+	Loops       []LoopState
+	DataLine    int
+	DataItem    int
+	// RndSeed and RndDraws put the random number generator back by
+	// seeding it and drawing RndDraws numbers.
+	RndSeed      int64
+	RndDraws     uint64
+	LastRnd      float64
+	NextFixedRnd int
+}
+
+// LoopState is an active FOR loop in a State.
+type LoopState struct {
+	VarName string
+	Limit   Value
+	Step    Value
+	// Line is the line of the FOR.
+	Line int
+}
+
+// Snapshot returns a copy of the interpreter's runtime state.
+func (bob *Interpreter) Snapshot() State {
+	s := State{
+		Line:         bob.CurrentLine(),
+		Variables:    map[string]Value{},
+		Arrays:       copyArrays(bob.Arrays),
+		ReturnStack:  append([]int(nil), bob.returnStack...),
+		DataLine:     bob.data.line,
+		DataItem:     bob.data.item,
+		LastRnd:      bob.lastRnd,
+		NextFixedRnd: bob.nextFixedRnd,
+	}
+	bob.lockVars()
+	for name, val := range bob.Variables {
+		s.Variables[name] = val
+	}
+	bob.unlockVars()
+	for _, frame := range bob.loopStack {
+		s.Loops = append(s.Loops, LoopState{
+			VarName: frame.varName,
+			Limit:   frame.limit,
+			Step:    frame.step,
+			Line:    frame.line,
+		})
+	}
+	if bob.rngSrc != nil {
+		s.RndSeed, s.RndDraws = bob.rngSrc.seed, bob.rngSrc.draws
+	}
+	return s
+}
+
+// Restore puts back the runtime state taken by Snapshot. The state is
+// copied, so changes made by running the program do not change s. The
+// program must still have the line s was taken at.
+func (bob *Interpreter) Restore(s State) error {
+	if s.Line == -1 {
+		if err := bob.buildInstructionIndex(); err != nil {
+			return err
+		}
+		bob.pc = len(bob.intructionIndex)
+	} else if err := bob.SetPC(s.Line); err != nil {
+		return err
+	}
+	vars := make(map[string]Value, len(s.Variables))
+	for name, val := range s.Variables {
+		vars[name] = val
+	}
+	bob.lockVars()
+	bob.Variables = vars
+	bob.unlockVars()
+	bob.Arrays = copyArrays(s.Arrays)
+	bob.returnStack = append([]int(nil), s.ReturnStack...)
+	bob.loopStack = nil
+	for _, loop := range s.Loops {
+		bob.loopStack = append(bob.loopStack, loopFrame{
+			varName: loop.VarName,
+			limit:   loop.Limit,
+			step:    loop.Step,
+			line:    loop.Line,
+		})
+	}
+	bob.data = dataPointer{line: s.DataLine, item: s.DataItem}
+	bob.Seed(s.RndSeed)
+	for i := uint64(0); i < s.RndDraws; i++ {
+		bob.rngSrc.Int63()
+	}
+	bob.lastRnd = s.LastRnd
+	bob.nextFixedRnd = s.NextFixedRnd
+	bob.atBreakpoint, bob.canContinue = false, false
+	return nil
+}
+
+// copyArrays returns a deep copy of arrays.
+func copyArrays(arrays map[string]*Array) map[string]*Array {
+	if arrays == nil {
+		return nil
+	}
+	c := make(map[string]*Array, len(arrays))
+	for name, arr := range arrays {
+		c[name] = &Array{
+			Base:   arr.Base,
+			Bounds: append([]int(nil), arr.Bounds...),
+			Values: append([]Value(nil), arr.Values...),
+		}
+	}
+	return c
+}
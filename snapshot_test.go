@@ -0,0 +1,135 @@
+package gdbasic
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// snapshotProgram has a loop, a GOSUB, an array, DATA and RND, so that all
+// of the state is in use when it stops at line 40.
+var snapshotProgram = []string{
+	`10 DATA 7, 8, 9`,
+	`20 DIM A(3)`,
+	`30 FOR I=1 TO 3`,
+	`40 GOSUB 100`,
+	`50 NEXT I`,
+	`60 PRINT S; A(1); A(2); A(3)`,
+	`70 PRINT INT(RND*1000)`,
+	`80 GOTO 200`,
+	`100 READ D`,
+	`110 LET A(I)=D`,
+	`120 LET S=S+D`,
+	`130 LET R=INT(RND*1000)`,
+	`140 RETURN`,
+	`200 REM`,
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	// the whole run, for comparison
+	want := runProgram(t, snapshotProgram...)
+
+	bob, out := newTestInterpreter(t, snapshotProgram...)
+	bob.SetBreakpoint(130)
+	if err := bob.Run(); err != ErrBreakpoint {
+		t.Fatalf("Run() = %v, want ErrBreakpoint", err)
+	}
+	bob.ClearBreakpoint(130)
+	snap := bob.Snapshot()
+	if snap.Line != 130 {
+		t.Errorf("snapshot is at line %d, want 130", snap.Line)
+	}
+	if len(snap.ReturnStack) != 1 || len(snap.Loops) != 1 {
+		t.Errorf("snapshot has return stack %v and loops %v, want one of each", snap.ReturnStack, snap.Loops)
+	}
+
+	// run on to the end, changing everything
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := bob.SetVar("S", Value{Int: -1}); err != nil {
+		t.Fatal(err)
+	}
+	bob.Arrays["A"].Values[1] = Value{Int: -1}
+	if snap.Variables["S"].Int != 7 || snap.Arrays["A"].Values[1].Int != 7 {
+		t.Errorf("snapshot changed with the interpreter: S=%v A(1)=%v", snap.Variables["S"], snap.Arrays["A"].Values[1])
+	}
+
+	if err := bob.Restore(snap); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := bob.CurrentLine(); got != 130 {
+		t.Errorf("restored to line %d, want 130", got)
+	}
+	if val, _ := bob.GetVar("S"); val.Int != 7 {
+		t.Errorf("restored S = %v, want 7", val)
+	}
+	if val, _ := bob.GetVar("I"); val.Int != 1 {
+		t.Errorf("restored I = %v, want 1", val)
+	}
+
+	// the restored state is copied too
+	bob.Arrays["A"].Values[1] = Value{Int: -2}
+	if snap.Arrays["A"].Values[1].Int != 7 {
+		t.Error("Restore aliased the snapshot's arrays")
+	}
+	bob.Arrays["A"].Values[1] = Value{Int: 7}
+
+	// running on from the restored state finishes the same way, with the
+	// same random numbers
+	first := out.String()
+	out.Reset()
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run after Restore: %v", err)
+	}
+	if first != out.String() || out.String() != want {
+		t.Errorf("printed %q then %q after Restore, want %q", first, out.String(), want)
+	}
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	bob, _ := newTestInterpreter(t, snapshotProgram...)
+	bob.SetBreakpoint(50)
+	if err := bob.Run(); err != ErrBreakpoint {
+		t.Fatalf("Run() = %v, want ErrBreakpoint", err)
+	}
+	snap := bob.Snapshot()
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got State
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(got, snap) {
+		t.Errorf("state after a JSON round trip is %+v, want %+v", got, snap)
+	}
+}
+
+func TestRestoreEnded(t *testing.T) {
+	bob, _ := newTestInterpreter(t, `10 LET A=1`, `20 PRINT A`)
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	snap := bob.Snapshot()
+	if snap.Line != -1 {
+		t.Errorf("snapshot of an ended program is at line %d, want -1", snap.Line)
+	}
+	if err := bob.Restore(snap); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := bob.CurrentLine(); got != -1 {
+		t.Errorf("restored to line %d, want -1", got)
+	}
+
+	// the line the snapshot was taken at must still be there
+	bob, _ = newTestInterpreter(t, `10 PRINT 1`, `20 PRINT 2`)
+	bob.SetBreakpoint(20)
+	bob.Run()
+	snap = bob.Snapshot()
+	bob.Delete(20, 20)
+	if err := bob.Restore(snap); err == nil {
+		t.Error("Restore to a deleted line: expected an error")
+	}
+}
@@ -0,0 +1,51 @@
+package gdbasic
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []string
+		trace   string
+		output  string
+	}{
+		{
+			name: "loop",
+			program: []string{
+				`10 TRON`,
+				`20 FOR I=1 TO 2`,
+				`30 PRINT I`,
+				`40 NEXT I`,
+				`50 TROFF`,
+				`60 PRINT "DONE"`,
+			},
+			trace:  "[20][30][40][30][40][50]",
+			output: "1\n2\nDONE\n",
+		},
+		{
+			name:    "off by default",
+			program: []string{`10 PRINT "A"`},
+			output:  "A\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bob, out := newTestInterpreter(t, tc.program...)
+			var trace bytes.Buffer
+			bob.TraceOutput = &trace
+			if err := bob.Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := trace.String(); got != tc.trace {
+				t.Errorf("traced %q, want %q", got, tc.trace)
+			}
+			// the trace is kept out of the program's output
+			if got := out.String(); got != tc.output {
+				t.Errorf("printed %q, want %q", got, tc.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package gdbasic
+
+import "math"
+
+// AngleMode is the unit the trigonometric functions work in.
+type AngleMode int
+
+const (
+	// Radians is the default AngleMode.
+	Radians AngleMode = iota
+	// Degrees is set by DEG.
+	Degrees
+)
+
+// toRadians converts an angle in the interpreter's AngleMode to radians.
+func (bob *Interpreter) toRadians(x float64) float64 {
+	if bob.AngleMode == Degrees {
+		return x * math.Pi / 180
+	}
+	return x
+}
+
+// fromRadians converts an angle in radians to the interpreter's AngleMode.
+func (bob *Interpreter) fromRadians(x float64) float64 {
+	if bob.AngleMode == Degrees {
+		return x * 180 / math.Pi
+	}
+	return x
+}
+
+// trigFunc returns a builtin that applies fn to its angle argument.
+func trigFunc(name string, fn func(float64) float64) builtinFunc {
+	return func(intp *Interpreter, args []Value) (Value, error) {
+		if err := checkArgs(name, args, "#"); err != nil {
+			return Value{}, err
+		}
+		return floatValue(fn(intp.toRadians(args[0].float()))), nil
+	}
+}
+
+// fnAtn returns the arctangent of a number, as an angle in the AngleMode.
+func fnAtn(intp *Interpreter, args []Value) (Value, error) {
+	if err := checkArgs("ATN", args, "#"); err != nil {
+		return Value{}, err
+	}
+	return floatValue(intp.fromRadians(math.Atan(args[0].float()))), nil
+}
+
+// DegInstruction makes the trigonometric functions work in degrees.
+type DegInstruction struct{}
+
+func (DegInstruction) Execute(intp *Interpreter) error {
+	intp.AngleMode = Degrees
+	return nil
+}
+
+func (DegInstruction) String() string { return "DEG" }
+
+// RadInstruction makes the trigonometric functions work in radians.
+type RadInstruction struct{}
+
+func (RadInstruction) Execute(intp *Interpreter) error {
+	intp.AngleMode = Radians
+	return nil
+}
+
+func (RadInstruction) String() string { return "RAD" }
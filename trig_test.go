@@ -0,0 +1,72 @@
+package gdbasic
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrig(t *testing.T) {
+	tests := []struct {
+		mode AngleMode
+		expr string
+		want float64
+	}{
+		{Radians, `SIN(3.14159265358979/2)`, 1},
+		{Radians, `SIN(0)`, 0},
+		{Radians, `COS(0)`, 1},
+		{Radians, `COS(3.14159265358979)`, -1},
+		{Radians, `TAN(3.14159265358979/4)`, 1},
+		{Radians, `ATN(1)`, math.Pi / 4},
+		{Radians, `4*ATN(1)`, math.Pi},
+		{Degrees, `SIN(90)`, 1},
+		{Degrees, `SIN(30)`, 0.5},
+		{Degrees, `COS(180)`, -1},
+		{Degrees, `COS(60)`, 0.5},
+		{Degrees, `TAN(45)`, 1},
+		{Degrees, `ATN(1)`, 45},
+		{Degrees, `ATN(-1)`, -45},
+		{Degrees, `SIN(ATN(1)*2)`, 1},
+	}
+	for _, tt := range tests {
+		bob := NewInterpreter()
+		bob.AngleMode = tt.mode
+		val, err := eval(t, bob, tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if !val.IsFloat {
+			t.Errorf("%s = %#v, want a float", tt.expr, val)
+		}
+		if math.Abs(val.Float-tt.want) > 1e-9 {
+			t.Errorf("%s in mode %d = %v, want %v", tt.expr, tt.mode, val.Float, tt.want)
+		}
+	}
+	testExprErrors(t, `SIN("A")`, `COS()`, `TAN(1, 2)`, `ATN("B")`)
+}
+
+func TestDegRad(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 PRINT INT(SIN(90)*1000)`,
+		`20 DEG`,
+		`30 PRINT SIN(90); " "; ATN(1)`,
+		`40 RAD`,
+		`50 PRINT SIN(0); " "; INT(ATN(1)*1000)`,
+	)
+	if bob.AngleMode != Radians {
+		t.Errorf("a new interpreter is in mode %d, want radians", bob.AngleMode)
+	}
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := "893\n1 45\n0 785\n"; out.String() != want {
+		t.Errorf("printed %q, want %q", out.String(), want)
+	}
+	if got, want := listing(t, bob), "10 PRINT INT(SIN(90)*1000)\n20 DEG\n30 PRINT SIN(90);\" \";ATN(1)\n40 RAD\n50 PRINT SIN(0);\" \";INT(ATN(1)*1000)\n"; got != want {
+		t.Errorf("listing is %q, want %q", got, want)
+	}
+	bob.AngleMode = Degrees
+	if got := bob.Clone().AngleMode; got != Degrees {
+		t.Errorf("clone has mode %d, want degrees", got)
+	}
+}
@@ -0,0 +1,322 @@
+package gdbasic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Value is a string, integer or float value.
+type Value struct {
+	Int     int
+	Float   float64
+	Str     string
+	IsStr   bool
+	IsFloat bool
+}
+
+func (v Value) String() string {
+	if v.IsStr {
+		return fmt.Sprintf(`"%s"`, strings.ReplaceAll(v.Str, `"`, `""`))
+	}
+	if v.IsFloat {
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%d", v.Int)
+}
+
+// Compare returns -1, 0 or 1 as v is less than, equal to or greater than
+// other. Numbers are compared numerically, integers being promoted to floats
+// when compared with one, and strings lexicographically. Comparing a string
+// with a number is an error.
+func (v Value) Compare(other Value) (int, error) {
+	switch {
+	case v.IsStr && other.IsStr:
+		return strings.Compare(v.Str, other.Str), nil
+	case v.IsStr || other.IsStr:
+		return 0, fmt.Errorf("type mismatch comparing %s and %s", v, other)
+	case !v.IsFloat && !other.IsFloat:
+		switch {
+		case v.Int < other.Int:
+			return -1, nil
+		case v.Int > other.Int:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	switch l, r := v.float(), other.float(); {
+	case l < r:
+		return -1, nil
+	case l > r:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (v Value) IntrepString(*Interpreter) (string, error) {
+	if v.IsStr {
+		return fmt.Sprintf("%s", v.Str), nil
+	}
+	return v.String(), nil
+}
+
+// Reference is a reference to a variable by name.
+type Reference string
+
+func (ref Reference) IntrepString(intp *Interpreter) (string, error) {
+	val, err := intp.variable(string(ref))
+	if err != nil {
+		return "", err
+	}
+	return val.IntrepString(intp)
+}
+
+func (ref Reference) String() string { return string(ref) }
+
+// IntrepreterStringer is something that can be printed given the state of
+// the interpreter.
+type IntrepreterStringer interface {
+	fmt.Stringer
+	IntrepString(*Interpreter) (string, error)
+}
+
+// IsString reports whether s is a single quoted string. A doubled quote ("")
+// inside the string is a literal quote.
+func IsString(s string) bool {
+	str := strings.TrimSpace(s)
+	if len(str) == 0 {
+		return true
+	}
+	if str[0] != '"' || len(str) < 2 {
+		return false
+	}
+	for i := 1; i < len(str); i++ {
+		if str[i] != '"' {
+			continue
+		}
+		if i+1 < len(str) && str[i+1] == '"' {
+			i++
+			continue
+		}
+		return i == len(str)-1
+	}
+	return false
+}
+func getString(s string) string {
+	str := strings.TrimSpace(s)
+	if len(str) <= 2 {
+		return ""
+	}
+	return strings.ReplaceAll(str[1:len(str)-1], `""`, `"`)
+}
+
+// cutQuoted cuts the double quoted string at the start of s, returning the
+// unquoted string and what follows it.
+func cutQuoted(s string) (str, rest string, ok bool) {
+	if len(s) == 0 || s[0] != '"' {
+		return "", s, false
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] != '"' {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '"' {
+			i++
+			continue
+		}
+		return getString(s[:i+1]), s[i+1:], true
+	}
+	return "", s, false
+}
+
+// splitOutsideQuotes splits s on any of the bytes in seps that are not inside
+// a double quoted string.
+func splitOutsideQuotes(s string, seps string) []string {
+	var parts []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && strings.IndexByte(seps, s[i]) != -1:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// cutKeyword slices s around the first kw that is outside a double quoted
+// string and is a word on its own, so cutting on TO does not match TOTAL.
+func cutKeyword(s, kw string) (before, after string, ok bool) {
+	inQuote := false
+	for i := 0; i+len(kw) <= len(s); i++ {
+		if s[i] == '"' {
+			inQuote = !inQuote
+			continue
+		}
+		if inQuote || s[i:i+len(kw)] != kw {
+			continue
+		}
+		if i > 0 && isIdentChar(s[i-1]) {
+			continue
+		}
+		if end := i + len(kw); end < len(s) && isIdentChar(s[end]) {
+			continue
+		}
+		return s[:i], s[i+len(kw):], true
+	}
+	return s, "", false
+}
+
+func strValue(s string) Value {
+	return Value{
+		Str:   s,
+		IsStr: true,
+	}
+}
+
+// variable returns the value of the named variable. A variable that has not
+// been set is an error if StrictVars is set, otherwise it is created with the
+// zero value for its type.
+func (bob *Interpreter) variable(name string) (Value, error) {
+	bob.lockVars()
+	defer bob.unlockVars()
+	if val, ok := bob.Variables[name]; ok {
+		return val, nil
+	}
+	if bob.StrictVars {
+		return Value{}, fmt.Errorf("unknown var: %v", name)
+	}
+	val := zeroValue(name)
+	bob.Variables[name] = val
+	return val, nil
+}
+
+// setVariable sets the named variable. With StringMemory set the text of a
+// string is stored in the simulated memory too, which can run out.
+func (bob *Interpreter) setVariable(name string, val Value) error {
+	if bob.StringMemory && val.IsStr {
+		if err := bob.storeString(name, val.Str); err != nil {
+			return err
+		}
+	}
+	bob.lockVars()
+	bob.Variables[name] = val
+	bob.unlockVars()
+	return nil
+}
+
+// checkWritable returns an error if the named variable is a constant made
+// by CONST, for the instructions that assign to variables.
+func (bob *Interpreter) checkWritable(name string) error {
+	if bob.constants[name] {
+		return fmt.Errorf("can not assign to constant %s", name)
+	}
+	return nil
+}
+
+// lockVars locks Variables when ThreadSafe is set.
+func (bob *Interpreter) lockVars() {
+	if bob.ThreadSafe {
+		bob.varsMu.Lock()
+	}
+}
+
+func (bob *Interpreter) unlockVars() {
+	if bob.ThreadSafe {
+		bob.varsMu.Unlock()
+	}
+}
+
+// GetVar returns the value of the named variable and whether it has been set.
+// It is safe to call while the program is running when ThreadSafe is set.
+func (bob *Interpreter) GetVar(name string) (Value, bool) {
+	bob.lockVars()
+	defer bob.unlockVars()
+	val, ok := bob.Variables[name]
+	return val, ok
+}
+
+// SetVar sets the named variable, which must be a string variable for a
+// string value and a numeric one otherwise. It is safe to call while the
+// program is running when ThreadSafe is set.
+func (bob *Interpreter) SetVar(name string, val Value) error {
+	if val.IsStr != isStringVar(name) {
+		return fmt.Errorf("type mismatch setting %s to %s", name, val)
+	}
+	return bob.setVariable(name, val)
+}
+
+// varName returns the name of a variable as written in a statement, in upper
+// case as the expression parser gives it, so that a and A are the same
+// variable.
+func varName(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// isStringVar reports whether the variable name is for a string.
+func isStringVar(name string) bool {
+	return strings.HasSuffix(name, "$")
+}
+
+// zeroValue returns the value a variable has before it is first set.
+func zeroValue(name string) Value {
+	if isStringVar(name) {
+		return strValue("")
+	}
+	return Value{}
+}
+
+// boolValue returns the BASIC truth value of b, -1 for true and 0 for false.
+func boolValue(b bool) Value {
+	if b {
+		return Value{Int: -1}
+	}
+	return Value{}
+}
+
+func floatValue(f float64) Value {
+	return Value{
+		Float:   f,
+		IsFloat: true,
+	}
+}
+
+// parseNumber parses a numeric literal, numbers with a decimal point or an
+// exponent, such as 1.5E3, are floats everything else is an integer.
+// Integers can be given in hex with a &H prefix, or octal with &O or just &,
+// and may have a leading sign.
+func parseNumber(s string) (Value, error) {
+	s = strings.TrimSpace(s)
+	unsigned := strings.TrimLeft(s, "+-")
+	if strings.HasPrefix(unsigned, "&") && len(s)-len(unsigned) <= 1 {
+		base, digits := 8, strings.ToUpper(unsigned[1:])
+		switch {
+		case strings.HasPrefix(digits, "H"):
+			base, digits = 16, digits[1:]
+		case strings.HasPrefix(digits, "O"):
+			digits = digits[1:]
+		}
+		i64, err := strconv.ParseInt(s[:len(s)-len(unsigned)]+digits, base, 32)
+		if err != nil {
+			return Value{}, fmt.Errorf("bad number `%s`", s)
+		}
+		return Value{Int: int(i64)}, nil
+	}
+	if strings.ContainsAny(s, ".Ee") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("bad number `%s`", s)
+		}
+		return floatValue(f), nil
+	}
+	i64, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return Value{}, fmt.Errorf("bad number `%s`", s)
+	}
+	return Value{
+		Int: int(i64),
+	}, nil
+}
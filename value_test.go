@@ -0,0 +1,255 @@
+package gdbasic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Value
+	}{
+		{"42", Value{Int: 42}},
+		{"+42", Value{Int: 42}},
+		{"-42", Value{Int: -42}},
+		{"2147483647", Value{Int: 2147483647}},
+		{"-2147483648", Value{Int: -2147483648}},
+		{"&HFF", Value{Int: 255}},
+		{"&hff", Value{Int: 255}},
+		{"-&H10", Value{Int: -16}},
+		{"&H7FFFFFFF", Value{Int: 2147483647}},
+		{"&O17", Value{Int: 15}},
+		{"&17", Value{Int: 15}},
+		{"+&O17", Value{Int: 15}},
+		{"1.5", floatValue(1.5)},
+		{"-1.5E3", floatValue(-1500)},
+	}
+	for _, tc := range tests {
+		got, err := parseNumber(tc.in)
+		if err != nil {
+			t.Errorf("parseNumber(%q): unexpected error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseNumber(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+	for _, in := range []string{"2147483648", "-2147483649", "&H80000000", "&O8", "&HG", "--1", "+-1", "1A", ""} {
+		if got, err := parseNumber(in); err == nil {
+			t.Errorf("parseNumber(%q) = %v, expected an error", in, got)
+		}
+	}
+}
+
+func TestIntegerLiteralRange(t *testing.T) {
+	got := runProgram(t,
+		`10 LET A=-2147483648`,
+		`20 LET B=2147483647`,
+		`30 LET C=&HFF + &O17 + +1`,
+		`40 READ D, E`,
+		`50 PRINT A; " "; B; " "; C; " "; D; " "; E; " "; -2^2`,
+		`60 DATA -2147483648, &H10`,
+	)
+	if want := "-2147483648 2147483647 271 -2147483648 16 -4\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+	for _, line := range []string{`LET A=2147483648`, `LET A=-2147483649`, `LET A=-(2147483648)`} {
+		if _, err := parseInstruction(10, line); err == nil {
+			t.Errorf("%s: expected an error", line)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		left, right Value
+		want        int
+	}{
+		// integers
+		{Value{Int: 1}, Value{Int: 2}, -1},
+		{Value{Int: 2}, Value{Int: 2}, 0},
+		{Value{Int: 3}, Value{Int: 2}, 1},
+		{Value{Int: -3}, Value{Int: 2}, -1},
+		// floats
+		{floatValue(1.5), floatValue(2.5), -1},
+		{floatValue(2.5), floatValue(2.5), 0},
+		{floatValue(3.5), floatValue(2.5), 1},
+		// integers are promoted when compared with a float
+		{Value{Int: 2}, floatValue(2.0), 0},
+		{floatValue(2.0), Value{Int: 2}, 0},
+		{Value{Int: 2}, floatValue(2.5), -1},
+		{floatValue(2.5), Value{Int: 2}, 1},
+		{Value{Int: 3}, floatValue(2.5), 1},
+		{floatValue(-0.5), Value{Int: 0}, -1},
+		// strings are compared byte by byte
+		{strValue("A"), strValue("B"), -1},
+		{strValue("B"), strValue("B"), 0},
+		{strValue("B"), strValue("A"), 1},
+		{strValue("AB"), strValue("ABC"), -1},
+		{strValue(""), strValue("A"), -1},
+		{strValue(""), strValue(""), 0},
+		{strValue("Z"), strValue("a"), -1},
+		{strValue("10"), strValue("9"), -1},
+	}
+	for _, tc := range tests {
+		got, err := tc.left.Compare(tc.right)
+		if err != nil {
+			t.Errorf("%v.Compare(%v): unexpected error: %v", tc.left, tc.right, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%v.Compare(%v) = %d, want %d", tc.left, tc.right, got, tc.want)
+		}
+	}
+}
+
+func TestCompareTypeMismatch(t *testing.T) {
+	for _, tc := range []struct{ left, right Value }{
+		{strValue("1"), Value{Int: 1}},
+		{Value{Int: 1}, strValue("1")},
+		{strValue(""), Value{}},
+		{floatValue(1.5), strValue("1.5")},
+	} {
+		if _, err := tc.left.Compare(tc.right); err == nil {
+			t.Errorf("%v.Compare(%v): expected a type mismatch", tc.left, tc.right)
+		}
+	}
+}
+
+func TestRelationalOperators(t *testing.T) {
+	ops := []string{"=", "<>", "<", ">", "<=", ">="}
+	tests := []struct {
+		left, right string
+		// want is the result of each of ops in turn
+		want [6]bool
+	}{
+		{`1`, `2`, [6]bool{false, true, true, false, true, false}},
+		{`2`, `2`, [6]bool{true, false, false, false, true, true}},
+		{`3`, `2`, [6]bool{false, true, false, true, false, true}},
+		{`2`, `2.0`, [6]bool{true, false, false, false, true, true}},
+		{`2.5`, `2`, [6]bool{false, true, false, true, false, true}},
+		{`"A"`, `"B"`, [6]bool{false, true, true, false, true, false}},
+		{`"B"`, `"B"`, [6]bool{true, false, false, false, true, true}},
+		{`"AB"`, `"A"`, [6]bool{false, true, false, true, false, true}},
+	}
+	bob := NewInterpreter()
+	for _, tc := range tests {
+		for i, op := range ops {
+			expr := tc.left + op + tc.right
+			val, err := eval(t, bob, expr)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", expr, err)
+				continue
+			}
+			if got := val.Int == -1; got != tc.want[i] || (!got && val.Int != 0) {
+				t.Errorf("%s = %v, want %v", expr, val, tc.want[i])
+			}
+		}
+	}
+	for _, expr := range []string{`"A"=1`, `1<"A"`} {
+		if val, err := eval(t, bob, expr); err == nil {
+			t.Errorf("%s = %v, expected a type mismatch", expr, val)
+		}
+	}
+}
+
+func TestIfCompare(t *testing.T) {
+	got := runProgram(t,
+		`10 IF 2=2.0 THEN PRINT "EQ"`,
+		`20 IF "APPLE"<"BANANA" THEN PRINT "LT"`,
+		`30 IF 3>2.5 THEN PRINT "GT"`,
+	)
+	if want := "EQ\nLT\nGT\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestUnsetVariables(t *testing.T) {
+	tests := []struct {
+		name string
+		want Value
+	}{
+		{"A", Value{}},
+		{"A%", Value{}},
+		{"A!", Value{}},
+		{"A#", Value{}},
+		{"A$", strValue("")},
+	}
+	for _, tc := range tests {
+		bob := NewInterpreter()
+		val, err := eval(t, bob, tc.name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if val != tc.want {
+			t.Errorf("unset %s = %#v, want %#v", tc.name, val, tc.want)
+		}
+
+		bob = NewInterpreter()
+		bob.StrictVars = true
+		if val, err := eval(t, bob, tc.name); err == nil {
+			t.Errorf("unset %s with StrictVars = %v, expected an error", tc.name, val)
+		}
+	}
+}
+
+func TestStrictVars(t *testing.T) {
+	program := []string{`10 LET A=1`, `20 PRINT A; B; "["; C$; "]"`}
+	if got, want := runProgram(t, program...), "10[]\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+
+	bob, out := newTestInterpreter(t, program...)
+	bob.StrictVars = true
+	err := bob.Run()
+	if err == nil || !strings.Contains(err.Error(), "unknown var: B") {
+		t.Errorf("Run() = %v, want an unknown var error for B", err)
+	}
+	// PRINT writes each item as it goes
+	if got, want := out.String(), "1"; got != want {
+		t.Errorf("printed %q before the error, want %q", got, want)
+	}
+
+	bob, _ = newTestInterpreter(t, `10 LET A=1`, `20 LET B$="X"`, `30 PRINT A; B$`)
+	bob.StrictVars = true
+	if err := bob.Run(); err != nil {
+		t.Errorf("set variables with StrictVars: %v", err)
+	}
+}
+
+func TestThreadSafe(t *testing.T) {
+	bob, _ := newTestInterpreter(t,
+		`10 LET X=X+1`,
+		`20 LET Z=Y`,
+		`30 IF X<2000 THEN 10`,
+	)
+	bob.ThreadSafe = true
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := bob.SetVar("Y", Value{Int: i}); err != nil {
+				t.Errorf("SetVar: %v", err)
+				return
+			}
+			bob.GetVar("X")
+		}
+	}()
+	err := bob.Run()
+	close(stop)
+	<-done
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if x, _ := bob.GetVar("X"); x.Int != 2000 {
+		t.Errorf("X = %v, want 2000", x)
+	}
+}
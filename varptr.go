@@ -0,0 +1,74 @@
+package gdbasic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// varBaseAddr is the address the first variable is given by VARPTR.
+const varBaseAddr = 0x0E00
+
+// varType returns the GW-BASIC type code of a variable, going by the suffix of
+// its name, which is also the number of bytes it takes up, apart from strings
+// whose descriptor takes 3.
+func varType(name string) int {
+	switch {
+	case strings.HasSuffix(name, "$"):
+		return 3
+	case strings.HasSuffix(name, "%"):
+		return 2
+	case strings.HasSuffix(name, "#"):
+		return 8
+	default:
+		return 4
+	}
+}
+
+// varAddr returns the simulated address of a variable. Addresses are handed out
+// in the order variables are first asked for, so they are the same from run
+// to run of a program.
+func (bob *Interpreter) varAddr(name string) int {
+	if addr, ok := bob.varAddrs[name]; ok {
+		return addr
+	}
+	if bob.varAddrs == nil {
+		bob.varAddrs = map[string]int{}
+		bob.nextVarAddr = varBaseAddr
+	}
+	addr := bob.nextVarAddr
+	bob.varAddrs[name] = addr
+	bob.nextVarAddr += varType(name)
+	return addr
+}
+
+// varptrArg returns the name of the variable passed to VARPTR or VARPTR$.
+func varptrArg(name string, args []Expression) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s: expected 1 argument got %d", name, len(args))
+	}
+	ref, ok := args[0].(Reference)
+	if !ok {
+		return "", fmt.Errorf("%s: `%s` is not a variable", name, args[0])
+	}
+	return string(ref), nil
+}
+
+// fnVarptr returns the simulated address of a variable.
+func fnVarptr(intp *Interpreter, args []Expression) (Value, error) {
+	name, err := varptrArg("VARPTR", args)
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Int: intp.varAddr(name)}, nil
+}
+
+// fnVarptrStr returns the type and simulated address of a variable as a three
+// byte string: the type code followed by the address, low byte first.
+func fnVarptrStr(intp *Interpreter, args []Expression) (Value, error) {
+	name, err := varptrArg("VARPTR$", args)
+	if err != nil {
+		return Value{}, err
+	}
+	addr := intp.varAddr(name)
+	return strValue(string([]byte{byte(varType(name)), byte(addr), byte(addr >> 8)})), nil
+}
@@ -0,0 +1,54 @@
+package gdbasic
+
+import "testing"
+
+func TestVarptr(t *testing.T) {
+	bob, out := newTestInterpreter(t,
+		`10 LET A=1`,
+		`20 LET B%=2`,
+		`30 PRINT VARPTR(A); " "; VARPTR(B%); " "; VARPTR(C$); " "; VARPTR(D)`,
+		`40 PRINT VARPTR(A); " "; VARPTR(B%)`,
+	)
+	// each variable follows the last, going by the size of its type
+	want := "3584 3588 3590 3593\n3584 3588\n"
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+
+	// a second run gives the same addresses
+	out.Reset()
+	if err := bob.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("second run printed %q, want %q", got, want)
+	}
+}
+
+func TestVarptrStr(t *testing.T) {
+	bob := NewInterpreter()
+	tests := []struct {
+		expr string
+		want string
+	}{
+		// the type code, then the address low byte first
+		{`VARPTR$(A)`, "\x04\x00\x0e"},
+		{`VARPTR$(B$)`, "\x03\x04\x0e"},
+		{`VARPTR$(C#)`, "\x08\x07\x0e"},
+		{`VARPTR$(A)`, "\x04\x00\x0e"},
+	}
+	for _, tc := range tests {
+		val, err := eval(t, bob, tc.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.expr, err)
+			continue
+		}
+		if val.Str != tc.want {
+			t.Errorf("%s = %q, want %q", tc.expr, val.Str, tc.want)
+		}
+	}
+	testExprErrors(t, `VARPTR(1)`, `VARPTR(A, B)`, `VARPTR$("A")`)
+}